@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 )
 
 // Config holds all configuration for the application
@@ -10,16 +12,36 @@ type Config struct {
 	Database DatabaseConfig
 	Server   ServerConfig
 	Risk     RiskConfig
+	Ingest   IngestConfig
+	Schedule ScheduleConfig
 }
 
+// Storage drivers
+const (
+	// DriverPostgres stores data in PostgreSQL
+	DriverPostgres = "postgres"
+	// DriverSQLite stores data in the SQLite file at Path
+	DriverSQLite = "sqlite"
+	// DriverMemory keeps data in memory until the process exits
+	DriverMemory = "memory"
+)
+
 // DatabaseConfig holds database configuration
 type DatabaseConfig struct {
+	// Driver selects the storage backend (postgres, sqlite or memory)
+	Driver string
+	// Path is the SQLite database file
+	Path string
+
 	Host     string
 	Port     string
 	User     string
 	Password string
 	DBName   string
 	SSLMode  string
+
+	// AutoMigrate applies pending schema migrations on startup
+	AutoMigrate bool
 }
 
 // ServerConfig holds server configuration
@@ -27,14 +49,97 @@ type ServerConfig struct {
 	Address string
 }
 
-// RiskConfig holds risk evaluation configuration
+// Risk scoring modes
+const (
+	// ScoringModeCount scores one point per tripped risk factor (0-3)
+	ScoringModeCount = "count"
+	// ScoringModeWeighted scores each factor by severity and weight (0-100)
+	ScoringModeWeighted = "weighted"
+	// ScoringModeRules scores with the rules loaded from RulesFile
+	ScoringModeRules = "rules"
+)
+
+// RiskConfig holds risk evaluation configuration. The JSON names, used by
+// POST /evaluate/preview, are those of the RISK_* settings.
 type RiskConfig struct {
-	AttendanceThreshold float64
-	AssignmentThreshold float64
-	ContactThreshold    int
-	LowRiskThreshold    int
-	MediumRiskThreshold int
-	HighRiskThreshold   int
+	AttendanceThreshold float64 `json:"attendance_threshold"`
+	AssignmentThreshold float64 `json:"assignment_threshold"`
+	ContactThreshold    int     `json:"contact_threshold"`
+	LowRiskThreshold    int     `json:"low_threshold"`
+	MediumRiskThreshold int     `json:"medium_threshold"`
+	HighRiskThreshold   int     `json:"high_threshold"`
+
+	// ScoringMode selects the scoring algorithm (count, weighted or rules)
+	ScoringMode string `json:"scoring_mode"`
+
+	// RulesFile is the YAML or JSON rules file used in rules mode; it is
+	// re-read whenever it changes, checked every RulesReloadSeconds (0 disables)
+	RulesFile          string `json:"-"`
+	RulesReloadSeconds int    `json:"-"`
+
+	// Factor weights and level thresholds used in weighted mode
+	AttendanceWeight        float64 `json:"attendance_weight"`
+	AssignmentWeight        float64 `json:"assignment_weight"`
+	ContactWeight           float64 `json:"contact_weight"`
+	WeightedMediumThreshold int     `json:"weighted_medium_threshold"`
+	WeightedHighThreshold   int     `json:"weighted_high_threshold"`
+
+	// Rate window: only records dated within WindowDays before AsOf and/or the
+	// latest WindowSessions records count; 0 disables either limit
+	WindowDays     int `json:"window_days"`
+	WindowSessions int `json:"window_sessions"`
+	// RecencyHalfLifeDays weights each record by 0.5^(age/half-life); 0 disables weighting
+	RecencyHalfLifeDays float64 `json:"recency_half_life_days"`
+	// AsOf is the YYYY-MM-DD reference date for windows and weighting; empty means today
+	AsOf string `json:"as_of"`
+
+	// Trend factor: compares the latest TrendWindowDays with the TrendWindowDays before
+	// and flags a drop of at least TrendDropThreshold percentage points, or a current run
+	// of at least ConsecutiveMissThreshold absences or missed assignments; 0 disables either check
+	TrendWindowDays          int     `json:"trend_window_days"`
+	TrendDropThreshold       float64 `json:"trend_drop_threshold"`
+	ConsecutiveMissThreshold int     `json:"consecutive_miss_threshold"`
+	TrendWeight              float64 `json:"trend_weight"`
+}
+
+// IngestConfig holds student data ingestion configuration
+type IngestConfig struct {
+	// DataFile is read by POST /evaluate when the request carries no body
+	DataFile string
+	// BatchSize is the number of students written by one bulk upsert, and
+	// committed together in stream mode and by partial jobs
+	BatchSize int
+	// JobWorkers is the number of asynchronous evaluation jobs run at once
+	JobWorkers int
+	// Concurrency is the number of goroutines scoring students in parallel
+	// during batch ingestion; 0 uses one per CPU
+	Concurrency int
+	// IdempotencyKeyTTLHours is how long the response to a POST /evaluate
+	// request with an Idempotency-Key header is kept for repeats of it
+	IdempotencyKeyTTLHours int
+	// IdempotencyKeyLeaseMinutes is how long a request with an Idempotency-Key
+	// header holds its key before a retry may claim it, in case it never completes
+	IdempotencyKeyLeaseMinutes int
+	// Columns maps columns of the CSV/XLSX long format to the headers used
+	// in imported files; unmapped columns use their own name as header
+	Columns map[string]string
+}
+
+// Re-evaluation scopes
+const (
+	// ScheduleScopeAll re-evaluates every student
+	ScheduleScopeAll = "all"
+	// ScheduleScopeChanged re-evaluates the students updated since the last successful run
+	ScheduleScopeChanged = "changed"
+)
+
+// ScheduleConfig holds scheduled re-evaluation configuration
+type ScheduleConfig struct {
+	// Cron is the schedule of re-evaluation runs as a five-field cron expression
+	// or a descriptor such as @daily or @every 6h; empty disables the schedule
+	Cron string
+	// Scope selects the students re-evaluated by each run (all or changed)
+	Scope string
 }
 
 // LoadConfig loads configuration from environment variables
@@ -42,12 +147,17 @@ type RiskConfig struct {
 func LoadConfig() *Config {
 	return &Config{
 		Database: DatabaseConfig{
+			Driver: getEnv("DB_DRIVER", DriverPostgres),
+			Path:   getEnv("DB_PATH", "mindx.db"),
+
 			Host:     getEnv("DB_HOST", "localhost"),
 			Port:     getEnv("DB_PORT", "5432"),
 			User:     getEnv("DB_USER", "postgres"),
 			Password: getEnv("DB_PASSWORD", "postgres"),
 			DBName:   getEnv("DB_NAME", "studentrisk"),
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+
+			AutoMigrate: getEnvBool("DB_AUTO_MIGRATE", true),
 		},
 		Server: ServerConfig{
 			Address: getEnv("SERVER_ADDRESS", ":8080"),
@@ -59,6 +169,39 @@ func LoadConfig() *Config {
 			LowRiskThreshold:    getEnvInt("RISK_LOW_THRESHOLD", 0),
 			MediumRiskThreshold: getEnvInt("RISK_MEDIUM_THRESHOLD", 2),
 			HighRiskThreshold:   getEnvInt("RISK_HIGH_THRESHOLD", 3),
+
+			ScoringMode:        getEnv("RISK_SCORING_MODE", ScoringModeCount),
+			RulesFile:          getEnv("RISK_RULES_FILE", "rules.yaml"),
+			RulesReloadSeconds: getEnvInt("RISK_RULES_RELOAD_SECONDS", 30),
+
+			AttendanceWeight:        getEnvFloat("RISK_ATTENDANCE_WEIGHT", 40.0),
+			AssignmentWeight:        getEnvFloat("RISK_ASSIGNMENT_WEIGHT", 35.0),
+			ContactWeight:           getEnvFloat("RISK_CONTACT_WEIGHT", 25.0),
+			WeightedMediumThreshold: getEnvInt("RISK_WEIGHTED_MEDIUM_THRESHOLD", 30),
+			WeightedHighThreshold:   getEnvInt("RISK_WEIGHTED_HIGH_THRESHOLD", 60),
+
+			WindowDays:          getEnvInt("RISK_WINDOW_DAYS", 0),
+			WindowSessions:      getEnvInt("RISK_WINDOW_SESSIONS", 0),
+			RecencyHalfLifeDays: getEnvFloat("RISK_RECENCY_HALF_LIFE_DAYS", 0),
+			AsOf:                getEnv("RISK_AS_OF", ""),
+
+			TrendWindowDays:          getEnvInt("RISK_TREND_WINDOW_DAYS", 0),
+			TrendDropThreshold:       getEnvFloat("RISK_TREND_DROP_THRESHOLD", 20.0),
+			ConsecutiveMissThreshold: getEnvInt("RISK_CONSECUTIVE_MISS_THRESHOLD", 0),
+			TrendWeight:              getEnvFloat("RISK_TREND_WEIGHT", 20.0),
+		},
+		Ingest: IngestConfig{
+			DataFile:                   getEnv("DATA_FILE", "data.json"),
+			BatchSize:                  getEnvInt("INGEST_BATCH_SIZE", 500),
+			JobWorkers:                 getEnvInt("INGEST_JOB_WORKERS", 2),
+			Concurrency:                getEnvInt("INGEST_CONCURRENCY", 0),
+			IdempotencyKeyTTLHours:     getEnvInt("IDEMPOTENCY_KEY_TTL_HOURS", 24),
+			IdempotencyKeyLeaseMinutes: getEnvInt("IDEMPOTENCY_KEY_LEASE_MINUTES", 15),
+			Columns:                    getEnvMap("INGEST_COLUMNS"),
+		},
+		Schedule: ScheduleConfig{
+			Cron:  getEnv("SCHEDULE_CRON", ""),
+			Scope: getEnv("SCHEDULE_SCOPE", ScheduleScopeAll),
 		},
 	}
 }
@@ -93,4 +236,25 @@ func getEnvInt(key string, defaultValue int) int {
 		}
 	}
 	return defaultValue
+}
+
+// Helper function to get boolean environment variable with default value
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if result, err := strconv.ParseBool(value); err == nil {
+			return result
+		}
+	}
+	return defaultValue
+}
+
+// Helper function to get environment variable holding comma-separated key=value pairs as a map
+func getEnvMap(key string) map[string]string {
+	result := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		if name, value, ok := strings.Cut(pair, "="); ok {
+			result[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+	return result
 }
\ No newline at end of file
@@ -2,35 +2,70 @@ package database
 
 import (
 	"fmt"
+	"log"
 
-	"mindx/config"
+	cfg "mindx/config"
 	"mindx/models"
 
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-// InitDB initializes database connection and performs migrations
-func InitDB(config config.DatabaseConfig) (*gorm.DB, error) {
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		config.Host, config.Port, config.User, config.Password, config.DBName, config.SSLMode,
-	)
+// Open connects to the configured database without touching the schema.
+// Only the postgres and sqlite drivers are backed by a database.
+func Open(config cfg.DatabaseConfig) (*gorm.DB, error) {
+	switch config.Driver {
+	case "", cfg.DriverPostgres:
+		dsn := fmt.Sprintf(
+			"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+			config.Host, config.Port, config.User, config.Password, config.DBName, config.SSLMode,
+		)
+		return gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	case cfg.DriverSQLite:
+		// Foreign keys are off by default in SQLite. Transactions take the write
+		// lock when they begin and wait for each other, so that job workers and
+		// requests sharing the file queue up instead of failing as locked.
+		return gorm.Open(sqlite.Open(config.Path+"?_foreign_keys=on&_busy_timeout=5000&_txlock=immediate"), &gorm.Config{})
+	default:
+		return nil, fmt.Errorf("database driver %q has no database to open", config.Driver)
+	}
+}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// InitDB initializes database connection and prepares the schema. Postgres
+// applies pending migrations unless config.AutoMigrate is off; SQLite, meant
+// for local development, creates its schema from the models.
+func InitDB(config cfg.DatabaseConfig) (*gorm.DB, error) {
+	db, err := Open(config)
 	if err != nil {
 		return nil, err
 	}
 
-	// Auto migrate models - first RiskEvaluation, then Student to avoid circular references
-	err = db.AutoMigrate(&models.RiskEvaluation{})
-	if err != nil {
-		return nil, err
+	if config.Driver == cfg.DriverSQLite {
+		err := db.AutoMigrate(
+			&models.Student{},
+			&models.RiskEvaluation{},
+			&models.AttendanceRecord{},
+			&models.AssignmentRecord{},
+			&models.ContactRecord{},
+			&models.Job{},
+			&models.IdempotencyKey{},
+			&models.Run{},
+		)
+		if err != nil {
+			return nil, err
+		}
+		return db, nil
 	}
-	
-	err = db.AutoMigrate(&models.Student{})
-	if err != nil {
-		return nil, err
+
+	if config.AutoMigrate {
+		applied, err := MigrateUp(db)
+		if err != nil {
+			return nil, err
+		}
+		for _, migration := range applied {
+			log.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+		}
 	}
 
 	return db, nil
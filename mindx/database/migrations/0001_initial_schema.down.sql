@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS contact_records;
+DROP TABLE IF EXISTS assignment_records;
+DROP TABLE IF EXISTS attendance_records;
+DROP TABLE IF EXISTS risk_evaluations;
+DROP TABLE IF EXISTS students;
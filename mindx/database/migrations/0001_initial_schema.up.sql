@@ -0,0 +1,85 @@
+-- Students, their risk evaluation history and activity records.
+-- Databases created by the former AutoMigrate start-up already have some of
+-- these tables, so everything is created only when missing and columns added
+-- since the first release are added to existing tables.
+
+CREATE TABLE IF NOT EXISTS students (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    student_id text,
+    student_name text,
+    dropout_score bigint,
+    dropout_risk_level text,
+    dropout_note text,
+    created_at bigint,
+    updated_at bigint,
+    deleted_at timestamptz
+);
+
+ALTER TABLE students
+    ADD COLUMN IF NOT EXISTS dropout_factors jsonb,
+    ADD COLUMN IF NOT EXISTS attendance_rate numeric,
+    ADD COLUMN IF NOT EXISTS assignment_rate numeric,
+    ADD COLUMN IF NOT EXISTS contact_failures bigint;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_students_student_id ON students (student_id);
+CREATE INDEX IF NOT EXISTS idx_students_deleted_at ON students (deleted_at);
+
+CREATE TABLE IF NOT EXISTS risk_evaluations (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    student_id uuid,
+    score bigint,
+    risk_level text,
+    note text,
+    created_at bigint,
+    updated_at bigint,
+    deleted_at timestamptz
+);
+
+ALTER TABLE risk_evaluations
+    ADD COLUMN IF NOT EXISTS factors jsonb,
+    ADD COLUMN IF NOT EXISTS attendance_rate numeric,
+    ADD COLUMN IF NOT EXISTS assignment_rate numeric,
+    ADD COLUMN IF NOT EXISTS contact_failures bigint,
+    ADD COLUMN IF NOT EXISTS scoring_mode text,
+    ADD COLUMN IF NOT EXISTS attendance_threshold numeric,
+    ADD COLUMN IF NOT EXISTS assignment_threshold numeric,
+    ADD COLUMN IF NOT EXISTS contact_threshold bigint,
+    ADD COLUMN IF NOT EXISTS medium_risk_threshold bigint,
+    ADD COLUMN IF NOT EXISTS high_risk_threshold bigint;
+
+CREATE INDEX IF NOT EXISTS idx_risk_evaluations_student_id ON risk_evaluations (student_id);
+CREATE INDEX IF NOT EXISTS idx_risk_evaluations_deleted_at ON risk_evaluations (deleted_at);
+
+CREATE TABLE IF NOT EXISTS attendance_records (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    student_id uuid NOT NULL,
+    date varchar(10) NOT NULL,
+    status text NOT NULL,
+    CONSTRAINT fk_students_attendance FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attendance_student_date ON attendance_records (student_id, date);
+CREATE INDEX IF NOT EXISTS idx_attendance_records_date ON attendance_records (date);
+
+CREATE TABLE IF NOT EXISTS assignment_records (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    student_id uuid NOT NULL,
+    date varchar(10) NOT NULL,
+    name text NOT NULL,
+    submitted boolean,
+    CONSTRAINT fk_students_assignments FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_assignment_student_name ON assignment_records (student_id, name);
+CREATE INDEX IF NOT EXISTS idx_assignment_records_date ON assignment_records (date);
+
+CREATE TABLE IF NOT EXISTS contact_records (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    student_id uuid NOT NULL,
+    date varchar(10) NOT NULL,
+    status text NOT NULL,
+    CONSTRAINT fk_students_contacts FOREIGN KEY (student_id) REFERENCES students (id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_contact_student_date ON contact_records (student_id, date);
+CREATE INDEX IF NOT EXISTS idx_contact_records_date ON contact_records (date);
@@ -0,0 +1,22 @@
+-- Copy activity records back into JSONB columns on students. The record
+-- tables are kept, as the current schema reads from them.
+
+ALTER TABLE students
+    ADD COLUMN IF NOT EXISTS attendance jsonb,
+    ADD COLUMN IF NOT EXISTS assignments jsonb,
+    ADD COLUMN IF NOT EXISTS contacts jsonb;
+
+UPDATE students s SET attendance = (
+    SELECT jsonb_agg(jsonb_build_object('date', a.date, 'status', a.status) ORDER BY a.date)
+    FROM attendance_records a WHERE a.student_id = s.id
+);
+
+UPDATE students s SET assignments = (
+    SELECT jsonb_agg(jsonb_build_object('date', a.date, 'name', a.name, 'submitted', a.submitted) ORDER BY a.date, a.name)
+    FROM assignment_records a WHERE a.student_id = s.id
+);
+
+UPDATE students s SET contacts = (
+    SELECT jsonb_agg(jsonb_build_object('date', c.date, 'status', c.status) ORDER BY c.date)
+    FROM contact_records c WHERE c.student_id = s.id
+);
@@ -0,0 +1,38 @@
+-- Move activity records of databases created before they had their own tables
+-- out of the JSONB columns on students, then drop those columns.
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'students' AND column_name = 'attendance') THEN
+        INSERT INTO attendance_records (student_id, date, status)
+        SELECT s.id, r.date, r.status
+        FROM students s, jsonb_to_recordset(s.attendance) AS r(date text, status text)
+        WHERE jsonb_typeof(s.attendance) = 'array'
+        ON CONFLICT DO NOTHING;
+
+        ALTER TABLE students DROP COLUMN attendance;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'students' AND column_name = 'assignments') THEN
+        INSERT INTO assignment_records (student_id, date, name, submitted)
+        SELECT s.id, r.date, r.name, COALESCE(r.submitted, false)
+        FROM students s, jsonb_to_recordset(s.assignments) AS r(date text, name text, submitted boolean)
+        WHERE jsonb_typeof(s.assignments) = 'array'
+        ON CONFLICT DO NOTHING;
+
+        ALTER TABLE students DROP COLUMN assignments;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'students' AND column_name = 'contacts') THEN
+        INSERT INTO contact_records (student_id, date, status)
+        SELECT s.id, r.date, r.status
+        FROM students s, jsonb_to_recordset(s.contacts) AS r(date text, status text)
+        WHERE jsonb_typeof(s.contacts) = 'array'
+        ON CONFLICT DO NOTHING;
+
+        ALTER TABLE students DROP COLUMN contacts;
+    END IF;
+END $$;
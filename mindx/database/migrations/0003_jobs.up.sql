@@ -0,0 +1,23 @@
+-- Evaluation jobs queued by POST /evaluate?async=true and processed by the worker pool.
+
+CREATE TABLE jobs (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    status varchar(20) NOT NULL,
+    mode varchar(20) NOT NULL,
+    format varchar(20) NOT NULL,
+    payload bytea,
+    total bigint NOT NULL DEFAULT 0,
+    processed bigint NOT NULL DEFAULT 0,
+    created bigint NOT NULL DEFAULT 0,
+    updated bigint NOT NULL DEFAULT 0,
+    failed bigint NOT NULL DEFAULT 0,
+    error text,
+    failures jsonb,
+    results jsonb,
+    created_at bigint,
+    updated_at bigint,
+    started_at bigint,
+    finished_at bigint
+);
+
+CREATE INDEX idx_jobs_status ON jobs (status);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS idempotency_keys;
+
+ALTER TABLE jobs DROP COLUMN IF EXISTS unchanged;
+
+ALTER TABLE students DROP COLUMN IF EXISTS input_hash;
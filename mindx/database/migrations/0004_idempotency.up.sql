@@ -0,0 +1,15 @@
+-- Content hashes that let evaluation runs skip unchanged students, and the
+-- stored responses of POST /evaluate requests sent with an Idempotency-Key.
+
+ALTER TABLE students ADD COLUMN input_hash varchar(64);
+
+ALTER TABLE jobs ADD COLUMN unchanged bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE idempotency_keys (
+    key varchar(255) PRIMARY KEY,
+    fingerprint varchar(64) NOT NULL,
+    status_code bigint NOT NULL DEFAULT 0,
+    location text,
+    response bytea,
+    created_at bigint
+);
@@ -0,0 +1,17 @@
+-- Scheduled re-evaluation runs, one row per run of the re-evaluation schedule.
+
+CREATE TABLE runs (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    status varchar(20) NOT NULL,
+    scope varchar(20) NOT NULL,
+    since bigint,
+    total bigint NOT NULL DEFAULT 0,
+    updated bigint NOT NULL DEFAULT 0,
+    unchanged bigint NOT NULL DEFAULT 0,
+    error text,
+    started_at bigint NOT NULL,
+    finished_at bigint
+);
+
+CREATE INDEX idx_runs_status ON runs (status);
+CREATE INDEX idx_runs_started_at ON runs (started_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_students_data_updated_at;
+
+ALTER TABLE students DROP COLUMN IF EXISTS data_updated_at;
@@ -0,0 +1,8 @@
+-- When the name or records of each student last changed, so that re-evaluation
+-- runs with the "changed" scope skip students whose evaluation alone was saved.
+
+ALTER TABLE students ADD COLUMN data_updated_at bigint NOT NULL DEFAULT 0;
+
+UPDATE students SET data_updated_at = COALESCE(updated_at, 0);
+
+CREATE INDEX idx_students_data_updated_at ON students (data_updated_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_risk_evaluations_recorded_at;
+
+ALTER TABLE risk_evaluations DROP COLUMN IF EXISTS recorded_at;
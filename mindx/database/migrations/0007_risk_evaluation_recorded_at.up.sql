@@ -0,0 +1,8 @@
+-- When each evaluation was saved, in nanoseconds, so that the history of a
+-- student stays oldest first when several evaluations share a created_at second.
+
+ALTER TABLE risk_evaluations ADD COLUMN recorded_at bigint NOT NULL DEFAULT 0;
+
+UPDATE risk_evaluations SET recorded_at = COALESCE(created_at, 0) * 1000000000;
+
+CREATE INDEX idx_risk_evaluations_recorded_at ON risk_evaluations (recorded_at);
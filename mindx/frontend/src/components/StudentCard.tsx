@@ -1,5 +1,6 @@
 import { useState } from 'react';
-import type { Student } from '../types';
+import type { RiskFactor, Student, StudentSummary } from '../types';
+import { getStudent } from '../services/api';
 import {
   Card,
   CardContent,
@@ -12,7 +13,8 @@ import {
   List,
   ListItem,
   ListItemText,
-  Divider
+  Divider,
+  CircularProgress
 } from '@mui/material';
 import ExpandMoreIcon from '@mui/icons-material/ExpandMore';
 import ExpandLessIcon from '@mui/icons-material/ExpandLess';
@@ -21,7 +23,7 @@ import ExpandLessIcon from '@mui/icons-material/ExpandLess';
 console.log('StudentCard component loaded');
 
 interface StudentCardProps {
-  student: Student;
+  student: StudentSummary;
 }
 
 const getRiskLevelColor = (riskLevel: string | null): string => {
@@ -37,22 +39,58 @@ const getRiskLevelColor = (riskLevel: string | null): string => {
   }
 };
 
+const formatFactorValue = (value: number | undefined, unit: string | undefined): string => {
+  if (value === undefined) {
+    return '';
+  }
+  return `${Number.isInteger(value) ? value : value.toFixed(1)}${unit || ''}`;
+};
+
+const describeFactor = (factor: RiskFactor): string => {
+  const value = formatFactorValue(factor.value, factor.unit);
+  const threshold = formatFactorValue(factor.threshold, factor.unit);
+  let label = factor.name;
+  if (value) {
+    label += ` ${value}`;
+  }
+  if (threshold) {
+    label += ` (threshold ${threshold})`;
+  }
+  return `${label}: +${Number.isInteger(factor.contribution) ? factor.contribution : factor.contribution.toFixed(1)}`;
+};
+
+const formatRate = (rate: number | null): string =>
+  rate === null ? 'N/A' : `${rate.toFixed(1)}%`;
+
 const StudentCard: React.FC<StudentCardProps> = ({ student }) => {
   const [expanded, setExpanded] = useState(false);
+  const [details, setDetails] = useState<Student | null>(null);
+  const [loadingDetails, setLoadingDetails] = useState(false);
 
-  const handleExpandClick = () => {
+  const handleExpandClick = async () => {
     setExpanded(!expanded);
+    // The list only carries summaries; load the full records on first expand
+    if (!expanded && !details && !loadingDetails) {
+      setLoadingDetails(true);
+      try {
+        setDetails(await getStudent(student.student_id));
+      } catch (err) {
+        console.error('Error loading student details:', err);
+      } finally {
+        setLoadingDetails(false);
+      }
+    }
   };
 
-  const attendanceRate = student.attendance.length > 0
-    ? (student.attendance.filter(a => a.status === 'ATTEND').length / student.attendance.length * 100).toFixed(1)
-    : '100';
+  const attendanceRate = formatRate(student.attendance_rate);
+  const assignmentRate = formatRate(student.assignment_rate);
+  const contactFailures = student.contact_failures ?? 'N/A';
 
-  const assignmentRate = student.assignments.length > 0
-    ? (student.assignments.filter(a => a.submitted).length / student.assignments.length * 100).toFixed(1)
-    : '100';
+  const attendance = details?.attendance ?? [];
+  const assignments = details?.assignments ?? [];
+  const contacts = details?.contacts ?? [];
 
-  const contactFailures = student.contacts.filter(c => c.status === 'FAILED').length;
+  const triggeredFactors = (student.dropout_factors || []).filter(f => f.triggered);
 
   console.log('Rendering StudentCard for:', student.student_id);
   
@@ -80,12 +118,26 @@ const StudentCard: React.FC<StudentCardProps> = ({ student }) => {
           <Typography variant="body2" color="text.secondary">
             Note: {student.dropout_note || 'No notes'}
           </Typography>
+          {triggeredFactors.length > 0 && (
+            <Box mt={1} display="flex" flexWrap="wrap" gap={1}>
+              {triggeredFactors.map(factor => (
+                <Chip
+                  key={factor.name}
+                  size="small"
+                  variant="outlined"
+                  color="error"
+                  label={describeFactor(factor)}
+                  title={factor.detail}
+                />
+              ))}
+            </Box>
+          )}
         </Box>
         
         <Box mt={1} display="flex" justifyContent="space-between" alignItems="center">
           <Box>
             <Typography variant="caption">
-              Attendance: {attendanceRate}% | Assignments: {assignmentRate}% | Contact Failures: {contactFailures}
+              Attendance: {attendanceRate} | Assignments: {assignmentRate} | Contact Failures: {contactFailures}
             </Typography>
           </Box>
           <IconButton onClick={handleExpandClick}>
@@ -94,12 +146,17 @@ const StudentCard: React.FC<StudentCardProps> = ({ student }) => {
         </Box>
         
         <Collapse in={expanded} timeout="auto" unmountOnExit>
+          {loadingDetails && (
+            <Box mt={2} display="flex" justifyContent="center">
+              <CircularProgress size={24} />
+            </Box>
+          )}
           <Box mt={2}>
             <Grid container spacing={2}>
               <Grid item xs={4}>
                 <Typography variant="subtitle2">Attendance</Typography>
                 <List dense>
-                  {student.attendance.slice(0, 5).map((a, index) => (
+                  {attendance.slice(0, 5).map((a, index) => (
                     <ListItem key={index} sx={{ py: 0 }}>
                       <ListItemText 
                         primary={a.date} 
@@ -110,9 +167,9 @@ const StudentCard: React.FC<StudentCardProps> = ({ student }) => {
                       />
                     </ListItem>
                   ))}
-                  {student.attendance.length > 5 && (
+                  {attendance.length > 5 && (
                     <ListItem sx={{ py: 0 }}>
-                      <ListItemText primary={`+${student.attendance.length - 5} more...`} />
+                      <ListItemText primary={`+${attendance.length - 5} more...`} />
                     </ListItem>
                   )}
                 </List>
@@ -123,7 +180,7 @@ const StudentCard: React.FC<StudentCardProps> = ({ student }) => {
               <Grid item xs={4}>
                 <Typography variant="subtitle2">Assignments</Typography>
                 <List dense>
-                  {student.assignments.map((a, index) => (
+                  {assignments.map((a, index) => (
                     <ListItem key={index} sx={{ py: 0 }}>
                       <ListItemText 
                         primary={a.name} 
@@ -142,7 +199,7 @@ const StudentCard: React.FC<StudentCardProps> = ({ student }) => {
               <Grid item xs={3}>
                 <Typography variant="subtitle2">Contact Attempts</Typography>
                 <List dense>
-                  {student.contacts.map((c, index) => (
+                  {contacts.map((c, index) => (
                     <ListItem key={index} sx={{ py: 0 }}>
                       <ListItemText 
                         primary={c.date} 
@@ -153,7 +210,7 @@ const StudentCard: React.FC<StudentCardProps> = ({ student }) => {
                       />
                     </ListItem>
                   ))}
-                  {student.contacts.length === 0 && (
+                  {contacts.length === 0 && (
                     <ListItem sx={{ py: 0 }}>
                       <ListItemText primary="No contact attempts" />
                     </ListItem>
@@ -1,5 +1,5 @@
 import { useState, useEffect } from 'react';
-import type { Student, RiskLevel, SortOption } from '../types';
+import type { StudentSummary, RiskLevel, SortOption } from '../types';
 import { getStudents, evaluateStudents } from '../services/api';
 import StudentCard from './StudentCard';
 import {
@@ -20,20 +20,24 @@ import type { SelectChangeEvent } from '@mui/material';
 console.log('StudentList component loaded');
 
 const StudentList: React.FC = () => {
-  const [students, setStudents] = useState<Student[]>([]);
+  const [students, setStudents] = useState<StudentSummary[]>([]);
   const [loading, setLoading] = useState<boolean>(true);
   const [error, setError] = useState<string | null>(null);
   const [riskFilter, setRiskFilter] = useState<RiskLevel>('');
   const [sortOption, setSortOption] = useState<SortOption>('');
   const [evaluating, setEvaluating] = useState<boolean>(false);
+  const [total, setTotal] = useState<number>(0);
+  const [nextCursor, setNextCursor] = useState<string | undefined>(undefined);
+  const [loadingMore, setLoadingMore] = useState<boolean>(false);
 
   const fetchStudents = async () => {
     console.log('Fetching students with filters:', { riskFilter, sortOption });
     setLoading(true);
     try {
-      const data = await getStudents(riskFilter, sortOption);
-      console.log('Students data received:', data);
-      setStudents(data);
+      const page = await getStudents(riskFilter, sortOption);
+      setStudents(page.students);
+      setTotal(page.total);
+      setNextCursor(page.next_cursor);
       setError(null);
     } catch (err) {
       console.error('Error fetching students:', err);
@@ -43,6 +47,26 @@ const StudentList: React.FC = () => {
     }
   };
 
+  // Append the next page of students for the current filters
+  const loadMore = async () => {
+    if (!nextCursor) {
+      return;
+    }
+    setLoadingMore(true);
+    try {
+      const page = await getStudents(riskFilter, sortOption, nextCursor);
+      setStudents((current) => [...current, ...page.students]);
+      setTotal(page.total);
+      setNextCursor(page.next_cursor);
+      setError(null);
+    } catch (err) {
+      console.error('Error fetching more students:', err);
+      setError('Failed to fetch more students. Please try again.');
+    } finally {
+      setLoadingMore(false);
+    }
+  };
+
   const handleEvaluate = async () => {
     console.log('Starting student evaluation');
     setEvaluating(true);
@@ -131,9 +155,21 @@ const StudentList: React.FC = () => {
           <CircularProgress />
         </Box>
       ) : students.length > 0 ? (
-        students.map((student) => (
-          <StudentCard key={student.id} student={student} />
-        ))
+        <>
+          {students.map((student) => (
+            <StudentCard key={student.id} student={student} />
+          ))}
+          <Box sx={{ display: 'flex', justifyContent: 'space-between', alignItems: 'center', my: 2 }}>
+            <Typography variant="body2" color="text.secondary">
+              Showing {students.length} of {total} students
+            </Typography>
+            {nextCursor && (
+              <Button variant="outlined" onClick={loadMore} disabled={loadingMore}>
+                {loadingMore ? <CircularProgress size={24} color="inherit" /> : 'Load More'}
+              </Button>
+            )}
+          </Box>
+        </>
       ) : (
         <Alert severity="info">No students found.</Alert>
       )}
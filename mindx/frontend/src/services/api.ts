@@ -1,5 +1,5 @@
 import axios from 'axios';
-import type { RiskLevel, SortOption, Student } from '../types';
+import type { RiskLevel, SortOption, Student, StudentPage, StudentSummary } from '../types';
 
 // Debug log
 console.log('API module loaded');
@@ -27,12 +27,16 @@ export const evaluateStudents = async (): Promise<Student[]> => {
   }
 };
 
+// Students fetched per page of the dashboard
+const STUDENT_PAGE_SIZE = 50;
+
+// getStudents fetches one page of students; pass the next_cursor of a page to get the one after it
 export const getStudents = async (
   riskLevel?: RiskLevel,
-  sortBy?: SortOption
-): Promise<Student[]> => {
+  sortBy?: SortOption,
+  cursor?: string
+): Promise<StudentPage> => {
   try {
-    let url = `${API_URL}/students`;
     const params = new URLSearchParams();
     
     if (riskLevel) {
@@ -42,18 +46,27 @@ export const getStudents = async (
     if (sortBy) {
       params.append('sort_by', sortBy);
     }
-    
-    const queryString = params.toString();
-    if (queryString) {
-      url += `?${queryString}`;
+
+    params.append('limit', String(STUDENT_PAGE_SIZE));
+
+    if (cursor) {
+      params.append('cursor', cursor);
     }
     
-    console.log('Fetching students from URL:', url);
-    const response = await axios.get(url);
-    console.log('API response data:', response.data);
+    const response = await axios.get<StudentPage>(`${API_URL}/students?${params.toString()}`);
     return response.data;
   } catch (error) {
     console.error('Error in getStudents:', error);
     throw error;
   }
+};
+
+export const getStudent = async (studentId: string): Promise<Student> => {
+  try {
+    const response = await axios.get<Student>(`${API_URL}/students/${encodeURIComponent(studentId)}`);
+    return response.data;
+  } catch (error) {
+    console.error('Error in getStudent:', error);
+    throw error;
+  }
 };
\ No newline at end of file
@@ -14,19 +14,47 @@ export interface ContactRecord {
   status: string;
 }
 
-export interface Student {
+export interface RiskFactor {
+  name: string;
+  value?: number;
+  unit?: string;
+  threshold?: number;
+  weight: number;
+  contribution: number;
+  triggered: boolean;
+  detail?: string;
+}
+
+// StudentSummary is the compact view returned by GET /students
+export interface StudentSummary {
   id: string;
   student_id: string;
   student_name: string;
-  attendance: AttendanceRecord[];
-  assignments: AssignmentRecord[];
-  contacts: ContactRecord[];
   dropout_score: number | null;
   dropout_risk_level: string | null;
   dropout_note: string | null;
+  dropout_factors: RiskFactor[] | null;
+  attendance_rate: number | null;
+  assignment_rate: number | null;
+  contact_failures: number | null;
   created_at: number;
   updated_at: number;
 }
 
+// Student is the full record returned by GET /students/:student_id
+export interface Student extends StudentSummary {
+  attendance: AttendanceRecord[] | null;
+  assignments: AssignmentRecord[] | null;
+  contacts: ContactRecord[] | null;
+}
+
+export interface StudentPage {
+  students: StudentSummary[];
+  total: number;
+  limit: number;
+  offset: number;
+  next_cursor?: string;
+}
+
 export type RiskLevel = 'LOW' | 'MEDIUM' | 'HIGH' | '';
 export type SortOption = 'risk_level' | 'risk_level_asc' | 'score' | 'score_asc' | '';
\ No newline at end of file
@@ -1,120 +1,850 @@
 package handlers
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"hash"
+	"io"
+	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 
+	"mindx/config"
 	"mindx/models"
+	"mindx/repository"
 	"mindx/services"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
-	"gorm.io/gorm"
+)
+
+// Ingestion modes accepted by POST /evaluate
+const (
+	ingestModeAtomic  = "atomic"
+	ingestModePartial = "partial"
+	ingestModeStream  = "stream"
+)
+
+// Headers of idempotent requests
+const (
+	headerIdempotencyKey     = "Idempotency-Key"
+	headerIdempotentReplayed = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
 )
 
 // Handler holds dependencies for HTTP handlers
 type Handler struct {
-	db      *gorm.DB
-	service *services.StudentService
+	service   *services.StudentService
+	dataFile  string
+	batchSize int
+	columns   services.ColumnMapping
+	jobs      *services.JobService
+	// risk is the scoring configuration previews start from
+	risk config.RiskConfig
+	// idempotency stores the responses to requests with an Idempotency-Key header
+	idempotency *services.IdempotencyService
 }
 
-// NewHandler creates a new Handler instance
-func NewHandler(db *gorm.DB) *Handler {
+// NewHandler creates a new Handler instance that evaluates students with scorer,
+// reads CSV and XLSX uploads with columns and queues asynchronous runs with jobs
+func NewHandler(cfg *config.Config, repo repository.Repository, scorer services.RiskScorer, columns services.ColumnMapping, jobs *services.JobService) *Handler {
+	ingest := cfg.Ingest
 	return &Handler{
-		db:      db,
-		service: services.NewStudentService(db),
+		service:   services.NewStudentServiceWithScorer(repo, scorer, ingest),
+		dataFile:  ingest.DataFile,
+		batchSize: ingest.BatchSize,
+		columns:   columns,
+		jobs:      jobs,
+		risk:      cfg.Risk,
+
+		idempotency: services.NewIdempotencyService(repo,
+			time.Duration(ingest.IdempotencyKeyTTLHours)*time.Hour,
+			time.Duration(ingest.IdempotencyKeyLeaseMinutes)*time.Minute),
 	}
 }
 
 // EvaluateRisk handles the POST /evaluate endpoint
-// It parses the JSON file, evaluates dropout risk, and stores results in the database
+// It reads the student data from the request (body or multipart "file" upload) as a
+// JSON array, newline-delimited JSON or a CSV/XLSX table in the long format, falling
+// back to the configured data file when no body is sent, evaluates dropout risk,
+// and stores results in the database
+// With async=true the run is queued as a job and 202 is returned with the job,
+// whose progress is polled at GET /jobs/:id
 func (h *Handler) EvaluateRisk(c echo.Context) error {
-	// Read JSON file
-	jsonFile, err := os.ReadFile("data.json")
+	// Open the student data sent with the request
+	body, format, err := openRequestData(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Failed to read request data: " + err.Error(),
+		})
+	}
+
+	// Fall back to the configured data file
+	if body == nil {
+		body, err = os.Open(h.dataFile)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to read data file: " + err.Error(),
+			})
+		}
+		format = services.FormatFromFileName(h.dataFile)
+	}
+	defer body.Close()
+
+	// An explicit format overrides the one implied by the request
+	if param := c.QueryParam("format"); param != "" {
+		format = param
+	}
+	switch format {
+	case services.FormatJSON, services.FormatNDJSON, services.FormatCSV, services.FormatXLSX:
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid format: must be " + services.FormatJSON + ", " + services.FormatNDJSON + ", " +
+				services.FormatCSV + " or " + services.FormatXLSX,
+		})
+	}
+
+	async := false
+	if param := c.QueryParam("async"); param != "" {
+		if async, err = strconv.ParseBool(param); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid async: must be true or false",
+			})
+		}
+	}
+
+	mode := c.QueryParam("mode")
+	switch mode {
+	case "", ingestModeAtomic, ingestModePartial:
+	case ingestModeStream:
+		if async {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "stream mode cannot run asynchronously",
+			})
+		}
+		return h.evaluateStream(c, body, format)
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid mode: must be " + ingestModeAtomic + ", " + ingestModePartial + " or " + ingestModeStream,
+		})
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Failed to read request data: " + err.Error(),
+		})
+	}
+	if async {
+		return h.enqueueEvaluation(c, mode, data, format)
+	}
+	if mode == ingestModePartial {
+		return h.evaluatePartial(c, data, format)
+	}
+
+	// Decode and validate student records
+	students, err := services.DecodeStudentsAs(format, data, h.columns)
+	if err != nil {
+		var validationErrs services.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			return c.JSON(http.StatusUnprocessableEntity, map[string]interface{}{
+				"error":   "Invalid student records",
+				"details": validationErrs,
+			})
+		}
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid " + strings.ToUpper(format) + " format: " + err.Error(),
+		})
+	}
+
+	// Process students and evaluate risk
+	results, err := h.service.ProcessAndEvaluateStudents(students)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to read JSON file: " + err.Error(),
+			"error": err.Error(),
 		})
 	}
 
-	// Parse raw JSON data
-	var rawStudents []map[string]interface{}
-	if err := json.Unmarshal(jsonFile, &rawStudents); err != nil {
+	return c.JSON(http.StatusOK, results)
+}
+
+// evaluatePartial evaluates every valid student in its own savepoint and
+// reports created, updated and failed students instead of failing the whole batch
+func (h *Handler) evaluatePartial(c echo.Context, data []byte, format string) error {
+	students, validationErrs, err := services.DecodeStudentsPartialAs(format, data, h.columns)
+	if err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{
-			"error": "Invalid JSON format: " + err.Error(),
+			"error": "Invalid " + strings.ToUpper(format) + " format: " + err.Error(),
 		})
 	}
-	
-	// Convert to proper Student models
-	var students []models.Student
-	for _, rawStudent := range rawStudents {
-		student := models.Student{
-			StudentID:   rawStudent["student_id"].(string),
-			StudentName: rawStudent["student_name"].(string),
-		}
-		
-		// Convert attendance to JSONB
-		if attendance, ok := rawStudent["attendance"]; ok {
-			attendanceBytes, err := json.Marshal(attendance)
-			if err != nil {
-				return c.JSON(http.StatusBadRequest, map[string]string{
-					"error": "Invalid attendance data",
-				})
+
+	report, err := h.service.ProcessAndEvaluateStudentsPartial(students)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	report.Failed = append(validationErrs.Failures(), report.Failed...)
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// enqueueEvaluation queues the evaluation of data as a job and returns it with
+// its location, leaving decoding and validation to the worker
+func (h *Handler) enqueueEvaluation(c echo.Context, mode string, data []byte, format string) error {
+	jobMode := models.JobModeAtomic
+	if mode == ingestModePartial {
+		jobMode = models.JobModePartial
+	}
+
+	job, err := h.jobs.Enqueue(jobMode, format, data)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, "/jobs/"+job.ID.String())
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// Idempotent is middleware making a POST endpoint safe to retry. The response to a
+// request with an Idempotency-Key header is stored, and a repeat of the request with
+// the same key gets it again, with an Idempotent-Replayed header, instead of being
+// processed again. Requests without the header pass through unchanged.
+func (h *Handler) Idempotent(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		key := strings.TrimSpace(c.Request().Header.Get(headerIdempotencyKey))
+		if key == "" {
+			return next(c)
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid Idempotency-Key: must be at most 255 characters",
+			})
+		}
+
+		// Identify the request by its target, media type and data. The body is
+		// hashed as it is read, so that it is never held in memory in full.
+		fingerprint := newRequestFingerprint(c)
+		stored, err := h.idempotency.Begin(key)
+		if err == nil && stored != nil {
+			// Only a repeat of the completed request gets its response
+			var sum string
+			if sum, err = fingerprint.Sum(); err == nil {
+				err = h.idempotency.Matches(stored, sum)
 			}
-			student.Attendance = attendanceBytes
-		}
-		
-		// Convert assignments to JSONB
-		if assignments, ok := rawStudent["assignments"]; ok {
-			assignmentsBytes, err := json.Marshal(assignments)
-			if err != nil {
-				return c.JSON(http.StatusBadRequest, map[string]string{
-					"error": "Invalid assignments data",
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrIdempotencyKeyReused):
+				return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+					"error": err.Error(),
 				})
-			}
-			student.Assignments = assignmentsBytes
-		}
-		
-		// Convert contacts to JSONB
-		if contacts, ok := rawStudent["contacts"]; ok {
-			contactsBytes, err := json.Marshal(contacts)
-			if err != nil {
-				return c.JSON(http.StatusBadRequest, map[string]string{
-					"error": "Invalid contacts data",
+			case errors.Is(err, services.ErrIdempotencyKeyInProgress):
+				return c.JSON(http.StatusConflict, map[string]string{
+					"error": err.Error(),
 				})
 			}
-			student.Contacts = contactsBytes
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		if stored != nil {
+			if stored.Location != "" {
+				c.Response().Header().Set(echo.HeaderLocation, stored.Location)
+			}
+			c.Response().Header().Set(headerIdempotentReplayed, "true")
+			return c.JSONBlob(stored.StatusCode, stored.Response)
+		}
+
+		// Release the key unless the response is stored, so that the client can
+		// retry with it. The deferred call also runs when next panics, before the
+		// panic reaches the Recover middleware.
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := h.idempotency.Abandon(key); err != nil {
+				log.Printf("Failed to release idempotency key %q: %v", key, err)
+			}
+		}()
+
+		// Process the request, recording the response
+		recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+		c.Response().Writer = recorder
+		err = next(c)
+		status := c.Response().Status
+		sum, sumErr := fingerprint.Sum()
+		if err != nil || status >= http.StatusInternalServerError || sumErr != nil {
+			return err
+		}
+		completed = true
+		location := c.Response().Header().Get(echo.HeaderLocation)
+		if err := h.idempotency.Complete(key, sum, status, location, recorder.body.Bytes()); err != nil {
+			log.Printf("Failed to store response for idempotency key %q: %v", key, err)
 		}
-		
-		students = append(students, student)
+		return nil
 	}
+}
 
-	// Process students and evaluate risk
-	results, err := h.service.ProcessAndEvaluateStudents(students)
+// requestFingerprint hashes the method, target, media type and data of a request
+type requestFingerprint struct {
+	hash hash.Hash
+	// body is the request body, copied to hash as it is read; nil for
+	// multipart uploads, whose file is hashed up front
+	body *hashingReader
+	err  error
+}
+
+// hashingReader copies what is read from a request body to a hash and
+// records whether the body was read to the end
+type hashingReader struct {
+	io.Reader
+	eof bool
+}
+
+// Read implements io.Reader
+func (r *hashingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err == io.EOF {
+		r.eof = true
+	}
+	return n, err
+}
+
+// newRequestFingerprint starts the fingerprint of the request of c. The data of a
+// multipart upload is the uploaded file, identified by the format of its name, as
+// the raw body holds a random boundary; any other body is hashed as it is read.
+func newRequestFingerprint(c echo.Context) *requestFingerprint {
+	req := c.Request()
+	f := &requestFingerprint{hash: sha256.New()}
+	f.hash.Write([]byte(req.Method + " " + req.URL.RequestURI() + "\n"))
+
+	contentType := req.Header.Get(echo.HeaderContentType)
+	if strings.HasPrefix(contentType, echo.MIMEMultipartForm) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			// The handler rejects the request
+			f.hash.Write([]byte(echo.MIMEMultipartForm + "\n"))
+			return f
+		}
+		f.hash.Write([]byte(services.FormatFromFileName(fileHeader.Filename) + "\n"))
+		file, err := fileHeader.Open()
+		if err != nil {
+			f.err = err
+			return f
+		}
+		defer file.Close()
+		_, f.err = io.Copy(f.hash, file)
+		return f
+	}
+
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	f.hash.Write([]byte(strings.TrimSpace(mediaType) + "\n"))
+	if req.Body != nil {
+		f.body = &hashingReader{Reader: io.TeeReader(req.Body, f.hash)}
+		req.Body = io.NopCloser(f.body)
+	}
+	return f
+}
+
+// Sum reads the rest of the body and returns the fingerprint. It fails once
+// a response was written to a request whose body was not read to the end,
+// as the server then closes the body.
+func (f *requestFingerprint) Sum() (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	if f.body != nil && !f.body.eof {
+		if _, err := io.Copy(io.Discard, f.body); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(f.hash.Sum(nil)), nil
+}
+
+// responseRecorder copies the body written to a response
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write implements http.ResponseWriter
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+// previewRequest is the body of POST /evaluate/preview
+type previewRequest struct {
+	// Risk overrides the configured RISK_* settings, named without the prefix
+	Risk json.RawMessage `json:"risk"`
+	// Rules replaces the rules file and selects rules mode
+	Rules *services.RuleFile `json:"rules"`
+}
+
+// PreviewEvaluation handles the POST /evaluate/preview endpoint
+// It scores every stored student with alternative risk settings or rules and
+// returns how each student's risk level would change, with aggregate counts,
+// without writing anything. With changed=true only changed students are listed.
+func (h *Handler) PreviewEvaluation(c echo.Context) error {
+	var req previewRequest
+	dec := json.NewDecoder(c.Request().Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil && err != io.EOF {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body: " + err.Error(),
+		})
+	}
+
+	changedOnly := false
+	if param := c.QueryParam("changed"); param != "" {
+		var err error
+		if changedOnly, err = strconv.ParseBool(param); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid changed: must be true or false",
+			})
+		}
+	}
+
+	scorer, err := services.NewPreviewScorer(h.risk, req.Risk, req.Rules)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	preview, err := h.service.PreviewEvaluations(scorer, changedOnly)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": err.Error(),
 		})
 	}
 
-	return c.JSON(http.StatusOK, results)
+	return c.JSON(http.StatusOK, preview)
+}
+
+// GetJob handles the GET /jobs/:id endpoint
+// It returns the status and progress of an evaluation job, with the rejected
+// students and a summary of every stored student once it finished
+func (h *Handler) GetJob(c echo.Context) error {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid job id",
+		})
+	}
+
+	job, err := h.jobs.GetJob(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Job not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, job)
+}
+
+// evaluateStream reads the students one at a time and commits them in batches,
+// so that memory use does not grow with the size of the data
+func (h *Handler) evaluateStream(c echo.Context, body io.Reader, format string) error {
+	if format != services.FormatJSON && format != services.FormatNDJSON {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid format: stream mode reads " + services.FormatJSON + " or " + services.FormatNDJSON,
+		})
+	}
+
+	report, err := h.service.ProcessStudentStream(services.NewStudentDecoder(body), h.batchSize)
+	if err != nil {
+		// Batches committed before the error stay stored; report them with the error
+		status := http.StatusInternalServerError
+		message := err.Error()
+		if errors.Is(err, services.ErrInvalidStream) {
+			status = http.StatusBadRequest
+		}
+		return c.JSON(status, map[string]interface{}{
+			"error":  message,
+			"report": report,
+		})
+	}
+
+	return c.JSON(http.StatusOK, report)
 }
 
 // ListStudents handles the GET /students endpoint
-// It lists all students with evaluated risks
-// Supports filtering by risk level and sorting
+// It lists students with evaluated risks one page at a time
+// Supports filtering by risk level, score, search text, update time and risk factor,
+// multi-field sorting, limit/offset and cursor pagination
 func (h *Handler) ListStudents(c echo.Context) error {
 	// Get query parameters
-	riskLevel := c.QueryParam("risk_level")
-	sortBy := c.QueryParam("sort_by")
-	
+	query := services.StudentQuery{
+		RiskLevels: listQueryParam(c, "risk_level"),
+		Search:     c.QueryParam("q"),
+		Factors:    listQueryParam(c, "factor"),
+		Fields:     listQueryParam(c, "fields"),
+		SortBy:     c.QueryParam("sort_by"),
+		Cursor:     c.QueryParam("cursor"),
+	}
+
+	var err error
+	if query.MinScore, err = optionalIntQueryParam(c, "min_score"); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid min_score: must be an integer",
+		})
+	}
+	if query.MaxScore, err = optionalIntQueryParam(c, "max_score"); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid max_score: must be an integer",
+		})
+	}
+	if since := c.QueryParam("updated_since"); since != "" {
+		updatedSince, err := parseTimestamp(since)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid updated_since: must be a Unix timestamp, RFC 3339 time or YYYY-MM-DD date",
+			})
+		}
+		query.UpdatedSince = &updatedSince
+	}
+	if query.Limit, err = intQueryParam(c, "limit"); err != nil || query.Limit < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid limit: must be a non-negative integer",
+		})
+	}
+	if query.Offset, err = intQueryParam(c, "offset"); err != nil || query.Offset < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid offset: must be a non-negative integer",
+		})
+	}
+	if query.Cursor != "" && query.Offset > 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "cursor and offset cannot be combined",
+		})
+	}
+
 	// Get students with filters
-	students, err := h.service.GetStudentsWithFilters(riskLevel, sortBy)
+	page, err := h.service.GetStudentsWithFilters(query)
 	if err != nil {
+		var queryErr *services.QueryError
+		if errors.Is(err, services.ErrInvalidCursor) || errors.As(err, &queryErr) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": err.Error(),
+			})
+		}
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": err.Error(),
 		})
 	}
 
-	return c.JSON(http.StatusOK, students)
-}
\ No newline at end of file
+	// Advertise the next page
+	c.Response().Header().Set("X-Total-Count", strconv.FormatInt(page.Total, 10))
+	if page.NextCursor != "" {
+		next := *c.Request().URL
+		params := next.Query()
+		params.Del("offset")
+		params.Set("cursor", page.NextCursor)
+		params.Set("limit", strconv.Itoa(page.Limit))
+		next.RawQuery = params.Encode()
+		c.Response().Header().Set("Link", "<"+next.RequestURI()+">; rel=\"next\"")
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+// intQueryParam parses an optional integer query parameter, returning 0 when absent
+func intQueryParam(c echo.Context, name string) (int, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// optionalIntQueryParam parses an optional integer query parameter, returning nil when absent
+func optionalIntQueryParam(c echo.Context, name string) (*int, error) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+// listQueryParam collects a query parameter given either repeatedly or as a comma-separated list
+func listQueryParam(c echo.Context, name string) []string {
+	var values []string
+	for _, param := range c.QueryParams()[name] {
+		for _, value := range strings.Split(param, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// parseTimestamp parses a Unix timestamp in seconds, an RFC 3339 time or a YYYY-MM-DD date
+func parseTimestamp(value string) (int64, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return seconds, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.Unix(), nil
+	}
+	t, err := time.Parse(models.DateLayout, value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
+}
+
+// dataContentTypes maps the content types of non-JSON bodies to their format
+var dataContentTypes = map[string]string{
+	"application/x-ndjson": services.FormatNDJSON,
+	"application/ndjson":   services.FormatNDJSON,
+	"text/csv":             services.FormatCSV,
+	"application/csv":      services.FormatCSV,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": services.FormatXLSX,
+}
+
+// openRequestData returns a reader over the student data sent with the request and its format.
+// Multipart requests must carry the data in the "file" form field, whose format
+// follows the file name's extension; any other non-empty body is the data itself,
+// in the format given by its content type and JSON by default.
+// It returns a nil reader when the request has no body.
+func openRequestData(c echo.Context) (io.ReadCloser, string, error) {
+	req := c.Request()
+	contentType := req.Header.Get(echo.HeaderContentType)
+	if strings.HasPrefix(contentType, echo.MIMEMultipartForm) {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", err
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		return file, services.FormatFromFileName(fileHeader.Filename), nil
+	}
+
+	if req.Body == nil {
+		return nil, "", nil
+	}
+
+	// Skip leading blanks to tell an empty body apart without reading all of it
+	body := bufio.NewReader(req.Body)
+	for {
+		b, err := body.ReadByte()
+		if err == io.EOF {
+			return nil, "", nil
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if !unicode.IsSpace(rune(b)) {
+			if err := body.UnreadByte(); err != nil {
+				return nil, "", err
+			}
+			break
+		}
+	}
+
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	if format, ok := dataContentTypes[strings.TrimSpace(mediaType)]; ok {
+		return io.NopCloser(body), format, nil
+	}
+	return io.NopCloser(body), services.FormatJSON, nil
+}
+
+// GetStudent handles the GET /students/:student_id endpoint
+// It returns one student with its full attendance, assignment and contact records
+func (h *Handler) GetStudent(c echo.Context) error {
+	student, err := h.service.GetStudent(c.Param("student_id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Student not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, student)
+}
+
+// CreateStudent handles the POST /students endpoint
+// It stores a single student from the JSON body and evaluates its dropout risk
+func (h *Handler) CreateStudent(c echo.Context) error {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Failed to read request data: " + err.Error(),
+		})
+	}
+
+	input, err := services.DecodeStudent(body)
+	if err != nil {
+		return studentInputError(c, err)
+	}
+
+	student, err := h.service.CreateStudent(input)
+	if err != nil {
+		if errors.Is(err, services.ErrStudentExists) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Student " + input.StudentID + " already exists",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusCreated, student)
+}
+
+// ReplaceStudent handles the PUT /students/:student_id endpoint
+// It replaces the student's name and records and re-evaluates its dropout risk
+func (h *Handler) ReplaceStudent(c echo.Context) error {
+	return h.updateStudent(c, true)
+}
+
+// PatchStudent handles the PATCH /students/:student_id endpoint
+// It changes only the fields present in the JSON body and re-evaluates the dropout risk
+func (h *Handler) PatchStudent(c echo.Context) error {
+	return h.updateStudent(c, false)
+}
+
+// updateStudent applies the request body to the student named in the path
+func (h *Handler) updateStudent(c echo.Context, replace bool) error {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Failed to read request data: " + err.Error(),
+		})
+	}
+
+	student, err := h.service.UpdateStudent(c.Param("student_id"), body, replace)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Student not found",
+			})
+		}
+		return studentInputError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, student)
+}
+
+// AppendAttendance handles the POST /students/:student_id/attendance endpoint
+func (h *Handler) AppendAttendance(c echo.Context) error {
+	return h.appendRecords(c, services.RecordTypeAttendance)
+}
+
+// AppendAssignments handles the POST /students/:student_id/assignments endpoint
+func (h *Handler) AppendAssignments(c echo.Context) error {
+	return h.appendRecords(c, services.RecordTypeAssignments)
+}
+
+// AppendContacts handles the POST /students/:student_id/contacts endpoint
+func (h *Handler) AppendContacts(c echo.Context) error {
+	return h.appendRecords(c, services.RecordTypeContacts)
+}
+
+// appendRecords appends the record or array of records in the request body to
+// the student named in the path and re-evaluates its dropout risk
+func (h *Handler) appendRecords(c echo.Context, recordType string) error {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Failed to read request data: " + err.Error(),
+		})
+	}
+
+	result, err := h.service.AppendStudentRecords(c.Param("student_id"), recordType, body)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Student not found",
+			})
+		}
+		return studentInputError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// DeleteStudent handles the DELETE /students/:student_id endpoint
+// It soft-deletes the student; its evaluation history is kept
+func (h *Handler) DeleteStudent(c echo.Context) error {
+	if err := h.service.DeleteStudent(c.Param("student_id")); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Student not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// studentInputError maps an error from decoding or storing a single student to a response
+func studentInputError(c echo.Context, err error) error {
+	var validationErrs services.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   "Invalid student record",
+			"details": validationErrs,
+		})
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid JSON format: " + err.Error(),
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": err.Error(),
+	})
+}
+
+// ListStudentEvaluations handles the GET /students/:student_id/evaluations endpoint
+// It returns the student's risk evaluation history, oldest first
+func (h *Handler) ListStudentEvaluations(c echo.Context) error {
+	evaluations, err := h.service.GetStudentEvaluations(c.Param("student_id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Student not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, evaluations)
+}
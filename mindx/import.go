@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"mindx/config"
+	"mindx/models"
+	"mindx/repository"
+	"mindx/services"
+)
+
+// importUsage documents the import subcommand
+const importUsage = "usage: mindx import [-format json|ndjson|csv|xlsx] [-partial | -stream] <file>"
+
+// runImport implements the import subcommand: it reads student data from a file,
+// or from standard input when the file is "-", and evaluates and stores it like
+// POST /evaluate. The format follows the file extension unless -format is given.
+// With -stream, JSON and NDJSON files are read one student at a time and
+// committed in batches of INGEST_BATCH_SIZE students.
+func runImport(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	format := flags.String("format", "", "data format: json, ndjson, csv or xlsx")
+	partial := flags.Bool("partial", false, "store valid students and report invalid ones")
+	stream := flags.Bool("stream", false, "read students one at a time and commit them in batches")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 || (*partial && *stream) {
+		return errors.New(importUsage)
+	}
+
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = services.FormatFromFileName(path)
+	}
+	switch *format {
+	case services.FormatJSON, services.FormatNDJSON:
+	case services.FormatCSV, services.FormatXLSX:
+		if *stream {
+			return fmt.Errorf("-stream reads %s or %s, not %s", services.FormatJSON, services.FormatNDJSON, *format)
+		}
+	default:
+		return fmt.Errorf("invalid format %q\n%s", *format, importUsage)
+	}
+
+	columns, err := services.NewColumnMapping(cfg.Ingest.Columns)
+	if err != nil {
+		return err
+	}
+	scorer, err := services.NewRiskScorer(&cfg.Risk)
+	if err != nil {
+		return err
+	}
+
+	var file io.ReadCloser = os.Stdin
+	if path != "-" {
+		if file, err = os.Open(path); err != nil {
+			return err
+		}
+	}
+	defer file.Close()
+
+	if *stream {
+		return importStream(cfg, scorer, file)
+	}
+
+	// Read and validate the file before touching the database
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	students, validationErrs, err := services.DecodeStudentsPartialAs(*format, data, columns)
+	if err != nil {
+		return err
+	}
+	if len(validationErrs) > 0 && !*partial {
+		for _, fieldErr := range validationErrs {
+			fmt.Fprintf(os.Stderr, "%d\t%s\t%s: %s\n", fieldErr.Index, fieldErr.StudentID, fieldErr.Field, fieldErr.Message)
+		}
+		return fmt.Errorf("%d invalid fields, nothing imported", len(validationErrs))
+	}
+
+	repo, err := repository.New(cfg.Database)
+	if err != nil {
+		return err
+	}
+	service := services.NewStudentServiceWithScorer(repo, scorer, cfg.Ingest)
+
+	if !*partial {
+		results, err := service.ProcessAndEvaluateStudents(students)
+		if err != nil {
+			return err
+		}
+		for i := range results {
+			printImported("evaluated", &results[i])
+		}
+		fmt.Printf("Imported %d students\n", len(results))
+		return nil
+	}
+
+	report, err := service.ProcessAndEvaluateStudentsPartial(students)
+	if err != nil {
+		return err
+	}
+	for i := range report.Created {
+		printImported("created", &report.Created[i])
+	}
+	for i := range report.Updated {
+		printImported("updated", &report.Updated[i])
+	}
+	for i := range report.Unchanged {
+		printImported("unchanged", &report.Unchanged[i])
+	}
+	report.Failed = append(validationErrs.Failures(), report.Failed...)
+	printFailures(report.Failed)
+	fmt.Printf("Created %d, updated %d, unchanged %d, failed %d students\n",
+		len(report.Created), len(report.Updated), len(report.Unchanged), len(report.Failed))
+	return nil
+}
+
+// importStream evaluates and stores the students read from file in batches
+func importStream(cfg *config.Config, scorer services.RiskScorer, file io.Reader) error {
+	repo, err := repository.New(cfg.Database)
+	if err != nil {
+		return err
+	}
+	service := services.NewStudentServiceWithScorer(repo, scorer, cfg.Ingest)
+
+	report, err := service.ProcessStudentStream(services.NewStudentDecoder(file), cfg.Ingest.BatchSize)
+	printFailures(report.Failed)
+	fmt.Printf("Created %d, updated %d, unchanged %d, failed %d students in %d batches\n",
+		report.Created, report.Updated, report.Unchanged, len(report.Failed), report.Batches)
+	return err
+}
+
+// printFailures prints the students that could not be imported with their errors
+func printFailures(failures []services.IngestionFailure) {
+	for _, failure := range failures {
+		fmt.Fprintf(os.Stderr, "failed\t%s\t%s\n", failure.StudentID, failure.Reason)
+		for _, fieldErr := range failure.Errors {
+			fmt.Fprintf(os.Stderr, "\t%d\t%s: %s\n", fieldErr.Index, fieldErr.Field, fieldErr.Message)
+		}
+	}
+}
+
+// printImported prints one imported student with its evaluated risk
+func printImported(action string, student *models.Student) {
+	level, score := "-", 0
+	if student.DropoutRiskLevel != nil {
+		level = *student.DropoutRiskLevel
+	}
+	if student.DropoutScore != nil {
+		score = *student.DropoutScore
+	}
+	fmt.Printf("%s\t%s\t%s\t%d\n", action, student.StudentID, level, score)
+}
@@ -1,25 +1,77 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"time"
 
 	"mindx/config"
-	"mindx/database"
+	"mindx/repository"
 	"mindx/router"
+	"mindx/services"
 )
 
 func main() {
 	// Load configuration
 	cfg := config.LoadConfig()
 
-	// Initialize database
-	db, err := database.InitDB(cfg.Database)
+	// Run the migrate subcommand instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg.Database, os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
+	// Run the import subcommand instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := runImport(cfg, os.Args[2:]); err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+		return
+	}
+
+	// Initialize storage
+	repo, err := repository.New(cfg.Database)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// Initialize risk scorer, validating the rules file in rules mode
+	scorer, err := services.NewRiskScorer(&cfg.Risk)
+	if err != nil {
+		log.Fatalf("Failed to initialize risk scorer: %v", err)
+	}
+	if rules, ok := scorer.(*services.RulesScorer); ok && cfg.Risk.RulesReloadSeconds > 0 {
+		go rules.Watch(context.Background(), time.Duration(cfg.Risk.RulesReloadSeconds)*time.Second)
+	}
+
+	// Validate the CSV/XLSX column mapping
+	columns, err := services.NewColumnMapping(cfg.Ingest.Columns)
+	if err != nil {
+		log.Fatalf("Invalid ingest column mapping: %v", err)
+	}
+
+	// Start the workers that run asynchronous evaluation jobs
+	students := services.NewStudentServiceWithScorer(repo, scorer, cfg.Ingest)
+	jobs := services.NewJobService(repo, students, columns, cfg.Ingest.BatchSize)
+	jobs.Start(context.Background(), cfg.Ingest.JobWorkers)
+
+	// Schedule re-evaluations of the stored students
+	if cfg.Schedule.Cron != "" {
+		reevaluation, err := services.NewReevaluationService(repo, students, cfg.Schedule.Scope)
+		if err != nil {
+			log.Fatalf("Failed to schedule re-evaluations: %v", err)
+		}
+		if err := reevaluation.Schedule(context.Background(), cfg.Schedule.Cron); err != nil {
+			log.Fatalf("Failed to schedule re-evaluations: %v", err)
+		}
+		log.Printf("Re-evaluating %s students on schedule %q", cfg.Schedule.Scope, cfg.Schedule.Cron)
+	}
+
 	// Initialize router
-	r := router.InitRouter(db)
+	r := router.InitRouter(cfg, repo, scorer, columns, jobs)
 
 	// Start server
 	log.Printf("Server starting on %s", cfg.Server.Address)
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"mindx/config"
+	"mindx/database"
+)
+
+// migrateUsage documents the migrate subcommand
+const migrateUsage = "usage: mindx migrate [up | down [steps] | status]"
+
+// runMigrate implements the migrate subcommand: "up" applies every pending
+// migration, "down" reverts the latest steps migrations (1 by default) and
+// "status" lists the migrations and when they were applied
+func runMigrate(cfg config.DatabaseConfig, args []string) error {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	steps := 1
+	switch {
+	case command != "up" && command != "down" && command != "status":
+		return errors.New(migrateUsage)
+	case command == "down" && len(args) == 2:
+		var err error
+		if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+			return fmt.Errorf("invalid number of steps %q\n%s", args[1], migrateUsage)
+		}
+	case len(args) > 1:
+		return errors.New(migrateUsage)
+	}
+
+	// SQLite is migrated with AutoMigrate on startup and the memory driver has no schema
+	if cfg.Driver != "" && cfg.Driver != config.DriverPostgres {
+		return fmt.Errorf("migrations only apply to the %s driver, not %s", config.DriverPostgres, cfg.Driver)
+	}
+
+	db, err := database.Open(cfg)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "up":
+		applied, err := database.MigrateUp(db)
+		for _, migration := range applied {
+			log.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			log.Printf("Database schema is up to date")
+		}
+		return err
+	case "down":
+		reverted, err := database.MigrateDown(db, steps)
+		for _, migration := range reverted {
+			log.Printf("Reverted migration %d_%s", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		statuses, err := database.MigrationStatuses(db)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, applied)
+		}
+		return nil
+	}
+	return nil
+}
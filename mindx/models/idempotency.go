@@ -0,0 +1,17 @@
+package models
+
+// IdempotencyKey records a request sent with an Idempotency-Key header and,
+// once it completed, its response, which is returned again for repeated requests
+type IdempotencyKey struct {
+	Key string `gorm:"primaryKey;size:255"`
+	// Fingerprint is a hash of the request, to tell a repeated request from
+	// a different one reusing the key. It is empty until the request completed,
+	// as the body is hashed while it is processed.
+	Fingerprint string `gorm:"size:64;not null"`
+	// StatusCode is 0 while the request is being processed
+	StatusCode int
+	// Location is the Location header of the response, if any
+	Location  string
+	Response  []byte
+	CreatedAt int64 `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// JobStatus is the state of an evaluation job
+type JobStatus string
+
+const (
+	JobStatusQueued    JobStatus = "queued"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusSucceeded JobStatus = "succeeded"
+	JobStatusFailed    JobStatus = "failed"
+)
+
+// Job modes, matching the ingestion modes of POST /evaluate
+const (
+	// JobModeAtomic stores every student or none
+	JobModeAtomic = "atomic"
+	// JobModePartial stores valid students and reports the others
+	JobModePartial = "partial"
+)
+
+// Job is an evaluation run queued by POST /evaluate and processed in the background
+type Job struct {
+	ID     uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	Status JobStatus `gorm:"size:20;not null;index" json:"status"`
+	// Mode and Format are the ingestion mode and data format of the request
+	Mode   string `gorm:"size:20;not null" json:"mode"`
+	Format string `gorm:"size:20;not null" json:"format"`
+	// Payload is the student data sent with the request
+	Payload []byte `json:"-"`
+
+	// Progress counts; Total, the number of students and rejected records,
+	// is known once the payload has been decoded
+	Total     int `json:"total"`
+	Processed int `json:"processed"`
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Failed    int `json:"failed"`
+
+	// Error is set when the job as a whole failed
+	Error string `json:"error,omitempty"`
+	// Failures lists the students that were rejected
+	Failures JSON `gorm:"type:jsonb" json:"failures,omitempty"`
+	// Results summarises every stored student once the job finished
+	Results JSON `gorm:"type:jsonb" json:"results,omitempty"`
+
+	CreatedAt  int64  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt  int64  `gorm:"autoUpdateTime" json:"updated_at"`
+	StartedAt  *int64 `json:"started_at,omitempty"`
+	FinishedAt *int64 `json:"finished_at,omitempty"`
+}
+
+// BeforeCreate assigns a new ID to a job unless one is set
+func (j *Job) BeforeCreate(tx *gorm.DB) error {
+	if j.ID == uuid.Nil {
+		j.ID = uuid.New()
+	}
+	return nil
+}
+
+// JSON is a JSON document stored as a JSONB column
+type JSON json.RawMessage
+
+// MarshalJSON implements json.Marshaler
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if j == nil {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (j *JSON) UnmarshalJSON(data []byte) error {
+	*j = append((*j)[:0], data...)
+	return nil
+}
+
+// Scan implements the sql.Scanner interface
+func (j *JSON) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append(JSON(nil), v...)
+	case string:
+		*j = JSON(v)
+	default:
+		return errors.New("invalid scan source")
+	}
+	return nil
+}
+
+// Value implements the driver.Valuer interface.
+// Documents are stored as JSON text, which every supported database accepts.
+func (j JSON) Value() (driver.Value, error) {
+	if j == nil {
+		return nil, nil
+	}
+	return string(j), nil
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RunStatus is the state of a scheduled re-evaluation run
+type RunStatus string
+
+const (
+	RunStatusRunning   RunStatus = "running"
+	RunStatusSucceeded RunStatus = "succeeded"
+	RunStatusFailed    RunStatus = "failed"
+)
+
+// Run is one scheduled re-evaluation of the stored students
+type Run struct {
+	ID     uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	Status RunStatus `gorm:"size:20;not null;index" json:"status"`
+	// Scope is "all" or "changed", for the students updated since the last successful run
+	Scope string `gorm:"size:20;not null" json:"scope"`
+	// Since is the update time from which a "changed" run selected students
+	Since *int64 `json:"since,omitempty"`
+
+	// Total is the number of students re-evaluated, of which Updated got a
+	// new evaluation and Unchanged kept the one they had
+	Total     int `json:"total"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+
+	// Error is set when the run failed
+	Error string `json:"error,omitempty"`
+
+	StartedAt  int64  `gorm:"not null;index" json:"started_at"`
+	FinishedAt *int64 `json:"finished_at,omitempty"`
+}
+
+// BeforeCreate assigns a new ID to a run unless one is set
+func (r *Run) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -18,124 +18,197 @@ const (
 	RiskLevelHigh   RiskLevel = "HIGH"
 )
 
+// RiskLevels lists the risk levels from lowest to highest
+var RiskLevels = []RiskLevel{RiskLevelLow, RiskLevelMedium, RiskLevelHigh}
+
+// Rank returns the position of the level in RiskLevels, from 1 for LOW,
+// or 0 if it is not a known risk level
+func (l RiskLevel) Rank() int {
+	for i, level := range RiskLevels {
+		if level == l {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Allowed status values for attendance and contact records
+const (
+	AttendanceStatusAttend = "ATTEND"
+	AttendanceStatusAbsent = "ABSENT"
+	ContactStatusSuccess   = "SUCCESS"
+	ContactStatusFailed    = "FAILED"
+)
+
+// DateLayout is the layout of every date field in student records (YYYY-MM-DD)
+const DateLayout = "2006-01-02"
+
 // Student represents a student in the database
 type Student struct {
-	ID              uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	StudentID       string         `gorm:"uniqueIndex" json:"student_id"`
-	StudentName     string         `json:"student_name"`
-	Attendance      JSONB          `gorm:"type:jsonb" json:"attendance"`
-	Assignments     JSONB          `gorm:"type:jsonb" json:"assignments"`
-	Contacts        JSONB          `gorm:"type:jsonb" json:"contacts"`
-	DropoutScore    *int           `json:"dropout_score"`
-	DropoutRiskLevel *string       `json:"dropout_risk_level"`
-	DropoutNote     *string        `json:"dropout_note"`
-	CreatedAt       int64          `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt       int64          `gorm:"autoUpdateTime" json:"updated_at"`
-	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               uuid.UUID          `gorm:"type:uuid;primary_key" json:"id"`
+	StudentID        string             `gorm:"uniqueIndex" json:"student_id"`
+	StudentName      string             `json:"student_name"`
+	Attendance       []AttendanceRecord `gorm:"foreignKey:StudentID;constraint:OnDelete:CASCADE" json:"attendance"`
+	Assignments      []AssignmentRecord `gorm:"foreignKey:StudentID;constraint:OnDelete:CASCADE" json:"assignments"`
+	Contacts         []ContactRecord    `gorm:"foreignKey:StudentID;constraint:OnDelete:CASCADE" json:"contacts"`
+	DropoutScore     *int               `json:"dropout_score"`
+	DropoutRiskLevel *string            `json:"dropout_risk_level"`
+	DropoutNote      *string            `json:"dropout_note"`
+	DropoutFactors   RiskFactors        `gorm:"type:jsonb" json:"dropout_factors"`
+	AttendanceRate   *float64           `json:"attendance_rate"`
+	AssignmentRate   *float64           `json:"assignment_rate"`
+	ContactFailures  *int               `json:"contact_failures"`
+	InputHash        *string            `gorm:"size:64" json:"-"`
+	// DataUpdatedAt is when the name or records of the student last changed;
+	// unlike UpdatedAt, saving an evaluation leaves it alone
+	DataUpdatedAt int64          `gorm:"not null;default:0;index" json:"-"`
+	CreatedAt     int64          `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     int64          `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // RiskEvaluation represents a risk evaluation in the database
 type RiskEvaluation struct {
-	ID        uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	StudentID uuid.UUID      `gorm:"type:uuid;index" json:"student_id"`
-	Score     int            `json:"score"`
-	RiskLevel RiskLevel      `json:"risk_level"`
-	Note      string         `json:"note"`
-	CreatedAt int64          `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt int64          `gorm:"autoUpdateTime" json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	StudentID uuid.UUID `gorm:"type:uuid;index" json:"student_id"`
+	Score     int       `json:"score"`
+	RiskLevel RiskLevel `json:"risk_level"`
+	Note      string    `json:"note"`
+	// Factors is the structured breakdown of how the score was reached
+	Factors RiskFactors `gorm:"type:jsonb" json:"factors"`
+
+	// Rates measured for this evaluation
+	AttendanceRate  float64 `json:"attendance_rate"`
+	AssignmentRate  float64 `json:"assignment_rate"`
+	ContactFailures int     `json:"contact_failures"`
+
+	// Scoring mode and thresholds in effect when the evaluation ran
+	ScoringMode         string  `json:"scoring_mode"`
+	AttendanceThreshold float64 `json:"attendance_threshold"`
+	AssignmentThreshold float64 `json:"assignment_threshold"`
+	ContactThreshold    int     `json:"contact_threshold"`
+	MediumRiskThreshold int     `json:"medium_risk_threshold"`
+	HighRiskThreshold   int     `json:"high_risk_threshold"`
+
+	CreatedAt int64 `gorm:"autoCreateTime" json:"created_at"`
+	// RecordedAt orders evaluations saved within the same second
+	RecordedAt int64          `gorm:"autoCreateTime:nano;index" json:"-"`
+	UpdatedAt  int64          `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
-// JSONB is a wrapper for handling JSON data in GORM
-type JSONB []byte
+// RiskFactor explains how one factor contributed to a risk evaluation
+type RiskFactor struct {
+	Name string `json:"name"`
+	// Value is the measured value, e.g. an attendance rate of 62.5 (%)
+	Value *float64 `json:"value,omitempty"`
+	// Unit is "%" for rates and empty for counts
+	Unit string `json:"unit,omitempty"`
+	// Threshold is the value at which the factor starts to count as risky
+	Threshold *float64 `json:"threshold,omitempty"`
+	// Weight is the most the factor can add to the score
+	Weight float64 `json:"weight"`
+	// Contribution is what the factor actually added to the score
+	Contribution float64 `json:"contribution"`
+	Triggered    bool    `json:"triggered"`
+	Detail       string  `json:"detail,omitempty"`
+}
+
+// RiskFactors is a list of RiskFactor stored as a JSONB column
+type RiskFactors []RiskFactor
 
 // Scan implements the sql.Scanner interface
-func (j *JSONB) Scan(value interface{}) error {
+func (f *RiskFactors) Scan(value interface{}) error {
 	if value == nil {
-		*j = nil
+		*f = nil
 		return nil
 	}
-	s, ok := value.([]byte)
-	if !ok {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
 		return errors.New("invalid scan source")
 	}
-	*j = append((*j)[0:0], s...)
-	return nil
+	return json.Unmarshal(data, f)
 }
 
-// Value implements the driver.Valuer interface
-func (j JSONB) Value() (driver.Value, error) {
-	if len(j) == 0 {
+// Value implements the driver.Valuer interface.
+// Factors are stored as JSON text, which every supported database accepts.
+func (f RiskFactors) Value() (driver.Value, error) {
+	if f == nil {
 		return nil, nil
 	}
-	return []byte(j), nil
-}
-
-// MarshalJSON returns the JSON encoding of JSONB
-func (j JSONB) MarshalJSON() ([]byte, error) {
-	if len(j) == 0 {
-		return []byte("null"), nil
-	}
-	return j, nil
-}
-
-// UnmarshalJSON sets JSONB to a copy of data
-func (j *JSONB) UnmarshalJSON(data []byte) error {
-	if j == nil {
-		return errors.New("null JSONB")
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
 	}
-	*j = append((*j)[0:0], data...)
-	return nil
+	return string(data), nil
 }
 
-// AttendanceRecord represents an attendance record
+// AttendanceRecord represents an attendance record, one per student and date
 type AttendanceRecord struct {
-	Date   string `json:"date"`
-	Status string `json:"status"`
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"-"`
+	StudentID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_attendance_student_date" json:"-"`
+	Date      string    `gorm:"size:10;not null;uniqueIndex:idx_attendance_student_date;index" json:"date"`
+	Status    string    `gorm:"not null" json:"status"`
 }
 
-// AssignmentRecord represents an assignment record
+// AssignmentRecord represents an assignment record, one per student and assignment name
 type AssignmentRecord struct {
-	Date      string `json:"date"`
-	Name      string `json:"name"`
-	Submitted bool   `json:"submitted"`
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"-"`
+	StudentID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_assignment_student_name" json:"-"`
+	Date      string    `gorm:"size:10;not null;index" json:"date"`
+	Name      string    `gorm:"not null;uniqueIndex:idx_assignment_student_name" json:"name"`
+	Submitted bool      `json:"submitted"`
 }
 
-// ContactRecord represents a contact record
+// ContactRecord represents a contact record, one per student and date
 type ContactRecord struct {
-	Date   string `json:"date"`
-	Status string `json:"status"`
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"-"`
+	StudentID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_contact_student_date" json:"-"`
+	Date      string    `gorm:"size:10;not null;uniqueIndex:idx_contact_student_date;index" json:"date"`
+	Status    string    `gorm:"not null" json:"status"`
 }
 
-// GetAttendanceRecords parses the attendance JSONB data
-func (s *Student) GetAttendanceRecords() ([]AttendanceRecord, error) {
-	var records []AttendanceRecord
-	if len(s.Attendance) > 0 {
-		if err := json.Unmarshal(s.Attendance, &records); err != nil {
-			return nil, err
-		}
+// BeforeCreate assigns a new ID to a student unless one is set
+func (s *Student) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
 	}
-	return records, nil
+	return nil
 }
 
-// GetAssignmentRecords parses the assignments JSONB data
-func (s *Student) GetAssignmentRecords() ([]AssignmentRecord, error) {
-	var records []AssignmentRecord
-	if len(s.Assignments) > 0 {
-		if err := json.Unmarshal(s.Assignments, &records); err != nil {
-			return nil, err
-		}
+// BeforeCreate assigns a new ID to an evaluation unless one is set
+func (e *RiskEvaluation) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
 	}
-	return records, nil
+	return nil
 }
 
-// GetContactRecords parses the contacts JSONB data
-func (s *Student) GetContactRecords() ([]ContactRecord, error) {
-	var records []ContactRecord
-	if len(s.Contacts) > 0 {
-		if err := json.Unmarshal(s.Contacts, &records); err != nil {
-			return nil, err
-		}
+// BeforeCreate assigns a new ID to an attendance record unless one is set
+func (r *AttendanceRecord) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate assigns a new ID to an assignment record unless one is set
+func (r *AssignmentRecord) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate assigns a new ID to a contact record unless one is set
+func (r *ContactRecord) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
 	}
-	return records, nil
-}
\ No newline at end of file
+	return nil
+}
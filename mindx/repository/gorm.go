@@ -0,0 +1,688 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"mindx/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormRepository is the Repository backed by a Postgres or SQLite database
+type GormRepository struct {
+	db *gorm.DB
+}
+
+// NewGormRepository creates a new GormRepository on db
+func NewGormRepository(db *gorm.DB) *GormRepository {
+	return &GormRepository{db: db}
+}
+
+// DB returns the underlying database handle
+func (r *GormRepository) DB() *gorm.DB {
+	return r.db
+}
+
+// recordAssociations maps the activity record fields to their associations,
+// with the order each association is loaded in
+var recordAssociations = map[string]struct {
+	name  string
+	order string
+}{
+	"attendance":  {"Attendance", "date"},
+	"assignments": {"Assignments", "date, name"},
+	"contacts":    {"Contacts", "date"},
+}
+
+// sortColumns maps each sort field to the column it reads
+var sortColumns = map[string]string{
+	SortStudentID:   "student_id",
+	SortStudentName: "student_name",
+	SortCreatedAt:   "created_at",
+	SortUpdatedAt:   "updated_at",
+	SortScore:       "dropout_score",
+	SortRiskLevel:   "dropout_risk_level",
+}
+
+// Transaction implements Repository. GORM runs nested transactions in a savepoint.
+func (r *GormRepository) Transaction(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&GormRepository{db: tx})
+	})
+}
+
+// FindStudent implements Repository
+func (r *GormRepository) FindStudent(studentID string, opts FindOptions) (*models.Student, error) {
+	query := r.db
+	if opts.IncludeDeleted {
+		query = query.Unscoped()
+	}
+	if opts.ForUpdate {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	if opts.WithRecords {
+		query = preloadRecords(query)
+	}
+
+	var student models.Student
+	if err := query.Where("student_id = ?", studentID).First(&student).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &student, nil
+}
+
+// FindStudents implements Repository
+func (r *GormRepository) FindStudents(studentIDs []string, opts FindOptions) ([]models.Student, error) {
+	query := r.db
+	if opts.IncludeDeleted {
+		query = query.Unscoped()
+	}
+	if opts.ForUpdate {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	if opts.WithRecords {
+		query = preloadRecords(query)
+	}
+
+	var found []models.Student
+	if err := query.Where("student_id IN ?", studentIDs).Find(&found).Error; err != nil {
+		return nil, err
+	}
+
+	// Return the students in the order asked for
+	byStudentID := make(map[string]int, len(found))
+	for i := range found {
+		byStudentID[found[i].StudentID] = i
+	}
+	students := make([]models.Student, 0, len(found))
+	for _, studentID := range studentIDs {
+		if i, ok := byStudentID[studentID]; ok {
+			students = append(students, found[i])
+		}
+	}
+	return students, nil
+}
+
+// ListStudents implements Repository
+func (r *GormRepository) ListStudents(opts ListOptions) ([]models.Student, int64, error) {
+	query := r.applyFilter(r.db.Model(&models.Student{}), opts.Filter)
+
+	// Count all matching students before paging
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Apply sorting
+	for _, key := range opts.Sort {
+		if key.Desc {
+			query = query.Order(sortExpr(key) + " DESC")
+		} else {
+			query = query.Order(sortExpr(key))
+		}
+	}
+
+	// Select the page
+	if opts.After != nil {
+		query = applyAfter(query, opts.Sort, opts.After)
+	}
+	if opts.Offset > 0 {
+		query = query.Offset(opts.Offset)
+	}
+	if opts.Limit > 0 {
+		query = query.Limit(opts.Limit)
+	}
+
+	// Load only the requested columns and activity records,
+	// always including the id that the activity records reference
+	if len(opts.Fields) > 0 {
+		var associations []string
+		columns := []string{"id"}
+		seen := map[string]bool{"id": true}
+		add := func(column string) {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+		for _, field := range opts.Fields {
+			if _, ok := recordAssociations[field]; ok {
+				associations = append(associations, field)
+			} else {
+				add(field)
+			}
+		}
+		for _, key := range opts.Sort {
+			add(sortColumns[key.Field])
+		}
+		query = query.Select(columns)
+		if len(associations) > 0 {
+			query = preloadRecords(query, associations...)
+		}
+	}
+
+	students := []models.Student{}
+	if err := query.Find(&students).Error; err != nil {
+		return nil, 0, err
+	}
+	return students, total, nil
+}
+
+// CreateStudent implements Repository
+func (r *GormRepository) CreateStudent(student *models.Student) error {
+	student.DataUpdatedAt = time.Now().Unix()
+	return r.db.Omit(clause.Associations).Create(student).Error
+}
+
+// UpdateStudent implements Repository
+func (r *GormRepository) UpdateStudent(id uuid.UUID, name string) error {
+	return r.db.Unscoped().Model(&models.Student{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"student_name":    name,
+		"input_hash":      nil,
+		"data_updated_at": time.Now().Unix(),
+		"deleted_at":      nil,
+	}).Error
+}
+
+// DeleteStudent implements Repository
+func (r *GormRepository) DeleteStudent(studentID string) error {
+	result := r.db.Where("student_id = ?", studentID).Delete(&models.Student{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// upsertColumns are the columns of an existing student overwritten by UpsertStudents
+var upsertColumns = []string{
+	"student_name", "dropout_score", "dropout_risk_level", "dropout_note", "dropout_factors",
+	"attendance_rate", "assignment_rate", "contact_failures", "input_hash", "data_updated_at", "updated_at", "deleted_at",
+}
+
+// recordBatchSize bounds the activity records inserted per statement,
+// keeping well below the Postgres limit of 65535 parameters
+const recordBatchSize = 1000
+
+// UpsertStudents implements Repository. Whatever the number of students, it runs
+// a fixed number of statements: one to look up existing students, one upsert
+// (INSERT ... ON CONFLICT (student_id) DO UPDATE), one delete per record table,
+// and batched inserts of the records and evaluations.
+func (r *GormRepository) UpsertStudents(students []models.Student, evaluations []models.RiskEvaluation) ([]bool, error) {
+	if len(students) == 0 {
+		return nil, nil
+	}
+
+	// Look up existing students, including deleted ones that keep their student_id
+	studentIDs := make([]string, len(students))
+	for i := range students {
+		studentIDs[i] = students[i].StudentID
+	}
+	var existing []models.Student
+	if err := r.db.Unscoped().Select("id", "student_id", "input_hash", "data_updated_at", "deleted_at").
+		Where("student_id IN ?", studentIDs).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	stored := make(map[string]models.Student, len(existing))
+	for _, student := range existing {
+		stored[student.StudentID] = student
+	}
+
+	now := time.Now().Unix()
+	created := make([]bool, len(students))
+	rows := make([]models.Student, len(students))
+	var existingIDs []uuid.UUID
+	for i := range students {
+		dataUpdatedAt := now
+		if student, ok := stored[students[i].StudentID]; ok {
+			students[i].ID = student.ID
+			created[i] = student.DeletedAt.Valid
+			existingIDs = append(existingIDs, student.ID)
+			if !created[i] && sameInput(&student, &students[i]) {
+				dataUpdatedAt = student.DataUpdatedAt
+			}
+		} else {
+			students[i].ID = uuid.New()
+			created[i] = true
+		}
+		evaluations[i].StudentID = students[i].ID
+
+		rows[i] = models.Student{
+			ID:            students[i].ID,
+			StudentID:     students[i].StudentID,
+			StudentName:   students[i].StudentName,
+			InputHash:     students[i].InputHash,
+			DataUpdatedAt: dataUpdatedAt,
+		}
+		applyEvaluation(&rows[i], &evaluations[i])
+	}
+
+	if err := r.db.Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "student_id"}},
+		DoUpdates: clause.AssignmentColumns(upsertColumns),
+	}).Create(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	// Replace the activity records of every student
+	if len(existingIDs) > 0 {
+		for _, model := range []interface{}{&models.AttendanceRecord{}, &models.AssignmentRecord{}, &models.ContactRecord{}} {
+			if err := r.db.Where("student_id IN ?", existingIDs).Delete(model).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+	var attendance []models.AttendanceRecord
+	var assignments []models.AssignmentRecord
+	var contacts []models.ContactRecord
+	for i := range students {
+		studentAttendance, studentAssignments, studentContacts := recordsOf(students[i].ID, &students[i])
+		attendance = append(attendance, studentAttendance...)
+		assignments = append(assignments, studentAssignments...)
+		contacts = append(contacts, studentContacts...)
+	}
+	if err := r.createRecords(attendance, assignments, contacts); err != nil {
+		return nil, err
+	}
+
+	// Append the evaluations to the history
+	if err := r.db.CreateInBatches(&evaluations, recordBatchSize).Error; err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// ReplaceRecords implements Repository
+func (r *GormRepository) ReplaceRecords(id uuid.UUID, records *models.Student) error {
+	if err := r.db.Where("student_id = ?", id).Delete(&models.AttendanceRecord{}).Error; err != nil {
+		return err
+	}
+	if err := r.db.Where("student_id = ?", id).Delete(&models.AssignmentRecord{}).Error; err != nil {
+		return err
+	}
+	if err := r.db.Where("student_id = ?", id).Delete(&models.ContactRecord{}).Error; err != nil {
+		return err
+	}
+	return r.AddRecords(id, records)
+}
+
+// AddRecords implements Repository
+func (r *GormRepository) AddRecords(id uuid.UUID, records *models.Student) error {
+	if err := r.db.Unscoped().Model(&models.Student{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"input_hash":      nil,
+		"data_updated_at": time.Now().Unix(),
+	}).Error; err != nil {
+		return err
+	}
+	return r.createRecords(recordsOf(id, records))
+}
+
+// createRecords inserts activity records in batches of recordBatchSize
+func (r *GormRepository) createRecords(attendance []models.AttendanceRecord, assignments []models.AssignmentRecord, contacts []models.ContactRecord) error {
+	if len(attendance) > 0 {
+		if err := r.db.CreateInBatches(&attendance, recordBatchSize).Error; err != nil {
+			return err
+		}
+	}
+	if len(assignments) > 0 {
+		if err := r.db.CreateInBatches(&assignments, recordBatchSize).Error; err != nil {
+			return err
+		}
+	}
+	if len(contacts) > 0 {
+		if err := r.db.CreateInBatches(&contacts, recordBatchSize).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveEvaluation implements Repository
+func (r *GormRepository) SaveEvaluation(evaluation *models.RiskEvaluation) error {
+	// Update student record with risk evaluation
+	if err := r.db.Model(&models.Student{}).Where("id = ?", evaluation.StudentID).Updates(map[string]interface{}{
+		"dropout_score":      evaluation.Score,
+		"dropout_risk_level": string(evaluation.RiskLevel),
+		"dropout_note":       evaluation.Note,
+		"dropout_factors":    evaluation.Factors,
+		"attendance_rate":    evaluation.AttendanceRate,
+		"assignment_rate":    evaluation.AssignmentRate,
+		"contact_failures":   evaluation.ContactFailures,
+	}).Error; err != nil {
+		return err
+	}
+
+	// Append evaluation to the student's history
+	return r.db.Create(evaluation).Error
+}
+
+// ListEvaluations implements Repository
+func (r *GormRepository) ListEvaluations(id uuid.UUID) ([]models.RiskEvaluation, error) {
+	query := r.db.Order("recorded_at, id")
+	if id != uuid.Nil {
+		query = query.Where("student_id = ?", id)
+	}
+
+	evaluations := []models.RiskEvaluation{}
+	if err := query.Find(&evaluations).Error; err != nil {
+		return nil, err
+	}
+	return evaluations, nil
+}
+
+// CreateJob implements Repository
+func (r *GormRepository) CreateJob(job *models.Job) error {
+	return r.db.Create(job).Error
+}
+
+// ClaimJob implements Repository. Postgres skips jobs locked by other claims;
+// SQLite serialises write transactions instead.
+func (r *GormRepository) ClaimJob() (*models.Job, error) {
+	var claimed *models.Job
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Find rather than First, which logs every poll finding no job
+		var job models.Job
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", models.JobStatusQueued).Order("created_at, id").Limit(1).Find(&job)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		now := time.Now().Unix()
+		job.Status = models.JobStatusRunning
+		job.StartedAt = &now
+		if err := tx.Model(&job).Updates(map[string]interface{}{
+			"status":     job.Status,
+			"started_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		claimed = &job
+		return nil
+	})
+	return claimed, err
+}
+
+// SaveJob implements Repository
+func (r *GormRepository) SaveJob(job *models.Job) error {
+	result := r.db.Model(job).Where("status = ?", models.JobStatusRunning).
+		Select("*").Omit("id", "payload", "created_at").Updates(job)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrJobNotRunning
+	}
+	return nil
+}
+
+// TouchJob implements Repository
+func (r *GormRepository) TouchJob(id uuid.UUID) error {
+	return r.db.Model(&models.Job{}).Where("id = ? AND status = ?", id, models.JobStatusRunning).
+		Update("updated_at", time.Now().Unix()).Error
+}
+
+// FailStaleJobs implements Repository
+func (r *GormRepository) FailStaleJobs(before int64, reason string) (int64, error) {
+	now := time.Now().Unix()
+	result := r.db.Model(&models.Job{}).
+		Where("status = ? AND updated_at < ?", models.JobStatusRunning, before).
+		Updates(map[string]interface{}{
+			"status":      models.JobStatusFailed,
+			"error":       reason,
+			"finished_at": now,
+			"updated_at":  now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// FindJob implements Repository
+func (r *GormRepository) FindJob(id uuid.UUID) (*models.Job, error) {
+	var job models.Job
+	if err := r.db.Omit("payload").Where("id = ?", id).First(&job).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &job, nil
+}
+
+// CreateIdempotencyKey implements Repository
+func (r *GormRepository) CreateIdempotencyKey(key *models.IdempotencyKey) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FindIdempotencyKey implements Repository
+func (r *GormRepository) FindIdempotencyKey(key string) (*models.IdempotencyKey, error) {
+	var found models.IdempotencyKey
+	if err := r.db.Where("key = ?", key).First(&found).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &found, nil
+}
+
+// SaveIdempotencyKey implements Repository
+func (r *GormRepository) SaveIdempotencyKey(key *models.IdempotencyKey) error {
+	return r.db.Model(key).Select("fingerprint", "status_code", "location", "response").Updates(key).Error
+}
+
+// DeleteIdempotencyKey implements Repository
+func (r *GormRepository) DeleteIdempotencyKey(key string) error {
+	return r.db.Where("key = ?", key).Delete(&models.IdempotencyKey{}).Error
+}
+
+// CreateRun implements Repository
+func (r *GormRepository) CreateRun(run *models.Run) error {
+	return r.db.Create(run).Error
+}
+
+// SaveRun implements Repository
+func (r *GormRepository) SaveRun(run *models.Run) error {
+	return r.db.Model(run).Select("*").Omit("id", "started_at").Updates(run).Error
+}
+
+// LastRun implements Repository
+func (r *GormRepository) LastRun(status models.RunStatus) (*models.Run, error) {
+	var run models.Run
+	if err := r.db.Where("status = ?", status).Order("started_at DESC, id").First(&run).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &run, nil
+}
+
+// TryLock implements Repository. On Postgres the advisory lock belongs to a
+// session, so a connection is set aside until the lock is released.
+func (r *GormRepository) TryLock(id int64) (func(), bool, error) {
+	if r.db.Dialector.Name() != "postgres" {
+		release, acquired := tryProcessLock(id)
+		return release, acquired, nil
+	}
+
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return nil, false, err
+	}
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", id).Scan(&acquired); err != nil || !acquired {
+		conn.Close()
+		return nil, false, err
+	}
+	release := func() {
+		conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", id)
+		conn.Close()
+	}
+	return release, true, nil
+}
+
+// applyFilter restricts query to the students matching filter
+func (r *GormRepository) applyFilter(query *gorm.DB, filter StudentFilter) *gorm.DB {
+	if len(filter.RiskLevels) > 0 {
+		query = query.Where("dropout_risk_level IN ?", filter.RiskLevels)
+	}
+	if filter.MinScore != nil {
+		query = query.Where("dropout_score >= ?", *filter.MinScore)
+	}
+	if filter.MaxScore != nil {
+		query = query.Where("dropout_score <= ?", *filter.MaxScore)
+	}
+
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		pattern := "%" + escapeLike(strings.ToLower(search)) + "%"
+		query = query.Where("(LOWER(student_id) LIKE ? ESCAPE '\\' OR LOWER(student_name) LIKE ? ESCAPE '\\')", pattern, pattern)
+	}
+
+	if filter.UpdatedSince != nil {
+		query = query.Where("updated_at >= ?", *filter.UpdatedSince)
+	}
+	if filter.DataUpdatedSince != nil {
+		query = query.Where("data_updated_at >= ?", *filter.DataUpdatedSince)
+	}
+
+	for _, factor := range filter.Factors {
+		if r.db.Dialector.Name() == "sqlite" {
+			query = query.Where("EXISTS (SELECT 1 FROM json_each(dropout_factors) "+
+				"WHERE json_extract(value, '$.name') = ? AND json_extract(value, '$.triggered') = 1)", factor)
+		} else {
+			query = query.Where("dropout_factors @> ?::jsonb", triggeredFactorFilter(factor))
+		}
+	}
+
+	return query
+}
+
+// preloadRecords loads the named activity record fields, or all of them when
+// none are named, with every query run on db
+func preloadRecords(db *gorm.DB, fields ...string) *gorm.DB {
+	if len(fields) == 0 {
+		fields = []string{"attendance", "assignments", "contacts"}
+	}
+	for _, field := range fields {
+		association := recordAssociations[field]
+		db = db.Preload(association.name, func(db *gorm.DB) *gorm.DB {
+			return db.Order(association.order)
+		})
+	}
+	return db
+}
+
+// recordsOf copies the activity records held by records, assigned to the student with id
+func recordsOf(id uuid.UUID, records *models.Student) ([]models.AttendanceRecord, []models.AssignmentRecord, []models.ContactRecord) {
+	attendance := make([]models.AttendanceRecord, len(records.Attendance))
+	for i, a := range records.Attendance {
+		a.ID, a.StudentID = uuid.Nil, id
+		attendance[i] = a
+	}
+	assignments := make([]models.AssignmentRecord, len(records.Assignments))
+	for i, a := range records.Assignments {
+		a.ID, a.StudentID = uuid.Nil, id
+		assignments[i] = a
+	}
+	contacts := make([]models.ContactRecord, len(records.Contacts))
+	for i, ct := range records.Contacts {
+		ct.ID, ct.StudentID = uuid.Nil, id
+		contacts[i] = ct
+	}
+	return attendance, assignments, contacts
+}
+
+// applyEvaluation sets the current risk of student from evaluation
+func applyEvaluation(student *models.Student, evaluation *models.RiskEvaluation) {
+	score := evaluation.Score
+	level := string(evaluation.RiskLevel)
+	note := evaluation.Note
+	attendanceRate := evaluation.AttendanceRate
+	assignmentRate := evaluation.AssignmentRate
+	contactFailures := evaluation.ContactFailures
+
+	student.DropoutScore = &score
+	student.DropoutRiskLevel = &level
+	student.DropoutNote = &note
+	student.DropoutFactors = append(models.RiskFactors(nil), evaluation.Factors...)
+	student.AttendanceRate = &attendanceRate
+	student.AssignmentRate = &assignmentRate
+	student.ContactFailures = &contactFailures
+}
+
+// sameInput reports whether the stored student holds the name and records of
+// input, as recorded by their input hashes
+func sameInput(stored, input *models.Student) bool {
+	return stored.InputHash != nil && input.InputHash != nil && *stored.InputHash == *input.InputHash
+}
+
+// sortExpr returns the SQL expression ordering students by key
+func sortExpr(key SortKey) string {
+	switch key.Field {
+	case SortScore:
+		return "COALESCE(dropout_score, " + strconv.FormatInt(unscored(key.Desc), 10) + ")"
+	case SortRiskLevel:
+		expr := "CASE dropout_risk_level"
+		for _, level := range models.RiskLevels {
+			expr += " WHEN '" + string(level) + "' THEN " + strconv.Itoa(level.Rank())
+		}
+		return expr + " ELSE " + strconv.FormatInt(unrankedRiskLevel(key.Desc), 10) + " END"
+	default:
+		return sortColumns[key.Field]
+	}
+}
+
+// applyAfter restricts query to the rows after the position with the given sort key values
+func applyAfter(query *gorm.DB, keys []SortKey, values []interface{}) *gorm.DB {
+	// (k0 > v0) OR (k0 = v0 AND k1 > v1) OR ..., with < for descending keys
+	var clauses []string
+	var args []interface{}
+	for i, key := range keys {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, sortExpr(keys[j])+" = ?")
+			args = append(args, values[j])
+		}
+		op := " > ?"
+		if key.Desc {
+			op = " < ?"
+		}
+		parts = append(parts, sortExpr(key)+op)
+		args = append(args, values[i])
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	return query.Where(strings.Join(clauses, " OR "), args...)
+}
+
+// triggeredFactorFilter builds the JSONB document matching a triggered factor by name
+func triggeredFactorFilter(name string) string {
+	filter, _ := json.Marshal([]map[string]interface{}{{"name": name, "triggered": true}})
+	return string(filter)
+}
+
+// escapeLike escapes the LIKE wildcards in s using backslash
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
+
+// notFound translates GORM's not found error into ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,18 @@
+package repository
+
+import "sync"
+
+// processLocks holds the locks taken by TryLock on backends without advisory
+// locks, keyed by lock ID
+var processLocks sync.Map
+
+// tryProcessLock takes the process-wide lock with id unless it is held,
+// reporting whether it did, and returns the function releasing it
+func tryProcessLock(id int64) (func(), bool) {
+	value, _ := processLocks.LoadOrStore(id, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	if !mu.TryLock() {
+		return nil, false
+	}
+	return mu.Unlock, true
+}
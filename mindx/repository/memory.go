@@ -0,0 +1,662 @@
+package repository
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mindx/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MemoryRepository is a Repository keeping everything in memory, for local
+// development and tests. Transactions hold an exclusive lock and work on a
+// copy of the data that replaces the original when they commit.
+type MemoryRepository struct {
+	mu    *sync.Mutex
+	state *memoryState
+	// inTx is set on the Repository passed to Transaction, which already holds mu
+	inTx bool
+}
+
+// memoryState is the data held by a MemoryRepository
+type memoryState struct {
+	// students are keyed by ID and include soft-deleted students
+	students    map[uuid.UUID]*models.Student
+	byStudentID map[string]uuid.UUID
+	evaluations []models.RiskEvaluation
+	// jobs are kept in the order they were created
+	jobs            []*models.Job
+	idempotencyKeys map[string]*models.IdempotencyKey
+	// runs are kept in the order they were created
+	runs []*models.Run
+}
+
+// NewMemoryRepository creates a new, empty MemoryRepository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		mu: &sync.Mutex{},
+		state: &memoryState{
+			students:        make(map[uuid.UUID]*models.Student),
+			byStudentID:     make(map[string]uuid.UUID),
+			idempotencyKeys: make(map[string]*models.IdempotencyKey),
+		},
+	}
+}
+
+// lock takes the repository lock unless the caller already holds it
+// in a transaction, and returns the function releasing it
+func (r *MemoryRepository) lock() func() {
+	if r.inTx {
+		return func() {}
+	}
+	r.mu.Lock()
+	return r.mu.Unlock
+}
+
+// Transaction implements Repository
+func (r *MemoryRepository) Transaction(fn func(repo Repository) error) error {
+	defer r.lock()()
+
+	tx := &MemoryRepository{mu: r.mu, state: r.state.clone(), inTx: true}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	*r.state = *tx.state
+	return nil
+}
+
+// FindStudent implements Repository. ForUpdate needs no extra locking,
+// as transactions already run one at a time.
+func (r *MemoryRepository) FindStudent(studentID string, opts FindOptions) (*models.Student, error) {
+	defer r.lock()()
+
+	id, ok := r.state.byStudentID[studentID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	student := r.state.students[id]
+	if student.DeletedAt.Valid && !opts.IncludeDeleted {
+		return nil, ErrNotFound
+	}
+
+	found := copyStudent(student)
+	if !opts.WithRecords {
+		found.Attendance, found.Assignments, found.Contacts = nil, nil, nil
+	}
+	return found, nil
+}
+
+// FindStudents implements Repository
+func (r *MemoryRepository) FindStudents(studentIDs []string, opts FindOptions) ([]models.Student, error) {
+	defer r.lock()()
+
+	students := []models.Student{}
+	for _, studentID := range studentIDs {
+		id, ok := r.state.byStudentID[studentID]
+		if !ok || (r.state.students[id].DeletedAt.Valid && !opts.IncludeDeleted) {
+			continue
+		}
+		found := copyStudent(r.state.students[id])
+		if !opts.WithRecords {
+			found.Attendance, found.Assignments, found.Contacts = nil, nil, nil
+		}
+		students = append(students, *found)
+	}
+	return students, nil
+}
+
+// ListStudents implements Repository. Every student is returned with all of its fields.
+func (r *MemoryRepository) ListStudents(opts ListOptions) ([]models.Student, int64, error) {
+	defer r.lock()()
+
+	var matching []*models.Student
+	for _, student := range r.state.students {
+		if !student.DeletedAt.Valid && matchesFilter(student, opts.Filter) {
+			matching = append(matching, student)
+		}
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		return compareKeys(opts.Sort, sortValues(opts.Sort, matching[i]), sortValues(opts.Sort, matching[j])) < 0
+	})
+	total := int64(len(matching))
+
+	// Select the page
+	if opts.After != nil {
+		start := sort.Search(len(matching), func(i int) bool {
+			return compareKeys(opts.Sort, sortValues(opts.Sort, matching[i]), opts.After) > 0
+		})
+		matching = matching[start:]
+	}
+	if opts.Offset > 0 {
+		if opts.Offset >= len(matching) {
+			matching = nil
+		} else {
+			matching = matching[opts.Offset:]
+		}
+	}
+	if opts.Limit > 0 && len(matching) > opts.Limit {
+		matching = matching[:opts.Limit]
+	}
+
+	students := make([]models.Student, len(matching))
+	for i, student := range matching {
+		students[i] = *copyStudent(student)
+	}
+	return students, total, nil
+}
+
+// CreateStudent implements Repository
+func (r *MemoryRepository) CreateStudent(student *models.Student) error {
+	defer r.lock()()
+
+	if _, ok := r.state.byStudentID[student.StudentID]; ok {
+		return errDuplicateStudent
+	}
+	if student.ID == uuid.Nil {
+		student.ID = uuid.New()
+	}
+	now := time.Now().Unix()
+	student.CreatedAt, student.UpdatedAt, student.DataUpdatedAt = now, now, now
+
+	stored := copyStudent(student)
+	stored.Attendance, stored.Assignments, stored.Contacts = nil, nil, nil
+	r.state.students[stored.ID] = stored
+	r.state.byStudentID[stored.StudentID] = stored.ID
+	return nil
+}
+
+// UpdateStudent implements Repository
+func (r *MemoryRepository) UpdateStudent(id uuid.UUID, name string) error {
+	defer r.lock()()
+
+	if student, ok := r.state.students[id]; ok {
+		student.StudentName = name
+		student.InputHash = nil
+		student.DeletedAt = gorm.DeletedAt{}
+		student.UpdatedAt = time.Now().Unix()
+		student.DataUpdatedAt = student.UpdatedAt
+	}
+	return nil
+}
+
+// DeleteStudent implements Repository
+func (r *MemoryRepository) DeleteStudent(studentID string) error {
+	defer r.lock()()
+
+	id, ok := r.state.byStudentID[studentID]
+	if !ok || r.state.students[id].DeletedAt.Valid {
+		return ErrNotFound
+	}
+	r.state.students[id].DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	return nil
+}
+
+// UpsertStudents implements Repository. Students are stored one by one, in a
+// transaction so that either all or none of them are.
+func (r *MemoryRepository) UpsertStudents(students []models.Student, evaluations []models.RiskEvaluation) ([]bool, error) {
+	created := make([]bool, len(students))
+	upsert := func(tx *MemoryRepository) error {
+		for i := range students {
+			student := &students[i]
+			// A student written again with the same name and records keeps its data timestamp
+			keepDataUpdatedAt := false
+			var dataUpdatedAt int64
+			if id, ok := tx.state.byStudentID[student.StudentID]; ok {
+				stored := tx.state.students[id]
+				created[i] = stored.DeletedAt.Valid
+				if !created[i] && sameInput(stored, student) {
+					keepDataUpdatedAt, dataUpdatedAt = true, stored.DataUpdatedAt
+				}
+				student.ID = id
+				if err := tx.UpdateStudent(id, student.StudentName); err != nil {
+					return err
+				}
+			} else {
+				created[i] = true
+				if err := tx.CreateStudent(student); err != nil {
+					return err
+				}
+			}
+			if err := tx.ReplaceRecords(student.ID, student); err != nil {
+				return err
+			}
+			tx.state.students[student.ID].InputHash = copyPtr(student.InputHash)
+			if keepDataUpdatedAt {
+				tx.state.students[student.ID].DataUpdatedAt = dataUpdatedAt
+			}
+
+			evaluations[i].StudentID = student.ID
+			if err := tx.SaveEvaluation(&evaluations[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Within a transaction, which is discarded on failure, the state is not copied again
+	var err error
+	if r.inTx {
+		err = upsert(r)
+	} else {
+		err = r.Transaction(func(repo Repository) error {
+			return upsert(repo.(*MemoryRepository))
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// ReplaceRecords implements Repository
+func (r *MemoryRepository) ReplaceRecords(id uuid.UUID, records *models.Student) error {
+	defer r.lock()()
+
+	if student, ok := r.state.students[id]; ok {
+		student.Attendance, student.Assignments, student.Contacts = recordsOf(id, records)
+		student.InputHash = nil
+		student.DataUpdatedAt = time.Now().Unix()
+	}
+	return nil
+}
+
+// AddRecords implements Repository
+func (r *MemoryRepository) AddRecords(id uuid.UUID, records *models.Student) error {
+	defer r.lock()()
+
+	if student, ok := r.state.students[id]; ok {
+		attendance, assignments, contacts := recordsOf(id, records)
+		student.Attendance = append(student.Attendance, attendance...)
+		student.Assignments = append(student.Assignments, assignments...)
+		student.Contacts = append(student.Contacts, contacts...)
+		student.InputHash = nil
+		student.DataUpdatedAt = time.Now().Unix()
+	}
+	return nil
+}
+
+// SaveEvaluation implements Repository
+func (r *MemoryRepository) SaveEvaluation(evaluation *models.RiskEvaluation) error {
+	defer r.lock()()
+
+	now := time.Now().Unix()
+	if student, ok := r.state.students[evaluation.StudentID]; ok {
+		applyEvaluation(student, evaluation)
+		student.UpdatedAt = now
+	}
+
+	if evaluation.ID == uuid.Nil {
+		evaluation.ID = uuid.New()
+	}
+	evaluation.CreatedAt, evaluation.UpdatedAt = now, now
+	evaluation.RecordedAt = time.Now().UnixNano()
+	r.state.evaluations = append(r.state.evaluations, *evaluation)
+	return nil
+}
+
+// ListEvaluations implements Repository
+func (r *MemoryRepository) ListEvaluations(id uuid.UUID) ([]models.RiskEvaluation, error) {
+	defer r.lock()()
+
+	// Evaluations are appended in order, so they are already oldest first
+	evaluations := []models.RiskEvaluation{}
+	for _, evaluation := range r.state.evaluations {
+		if id == uuid.Nil || evaluation.StudentID == id {
+			evaluations = append(evaluations, evaluation)
+		}
+	}
+	return evaluations, nil
+}
+
+// CreateJob implements Repository
+func (r *MemoryRepository) CreateJob(job *models.Job) error {
+	defer r.lock()()
+
+	if job.ID == uuid.Nil {
+		job.ID = uuid.New()
+	}
+	now := time.Now().Unix()
+	job.CreatedAt, job.UpdatedAt = now, now
+
+	stored := *job
+	r.state.jobs = append(r.state.jobs, &stored)
+	return nil
+}
+
+// ClaimJob implements Repository
+func (r *MemoryRepository) ClaimJob() (*models.Job, error) {
+	defer r.lock()()
+
+	for _, job := range r.state.jobs {
+		if job.Status == models.JobStatusQueued {
+			now := time.Now().Unix()
+			job.Status = models.JobStatusRunning
+			job.StartedAt = &now
+			job.UpdatedAt = now
+			claimed := *job
+			return &claimed, nil
+		}
+	}
+	return nil, nil
+}
+
+// SaveJob implements Repository
+func (r *MemoryRepository) SaveJob(job *models.Job) error {
+	defer r.lock()()
+
+	for _, stored := range r.state.jobs {
+		if stored.ID == job.ID {
+			if stored.Status != models.JobStatusRunning {
+				return ErrJobNotRunning
+			}
+			payload, createdAt := stored.Payload, stored.CreatedAt
+			*stored = *job
+			stored.Payload, stored.CreatedAt = payload, createdAt
+			stored.UpdatedAt = time.Now().Unix()
+			job.UpdatedAt = stored.UpdatedAt
+			return nil
+		}
+	}
+	return ErrJobNotRunning
+}
+
+// TouchJob implements Repository
+func (r *MemoryRepository) TouchJob(id uuid.UUID) error {
+	defer r.lock()()
+
+	for _, job := range r.state.jobs {
+		if job.ID == id && job.Status == models.JobStatusRunning {
+			job.UpdatedAt = time.Now().Unix()
+		}
+	}
+	return nil
+}
+
+// FailStaleJobs implements Repository
+func (r *MemoryRepository) FailStaleJobs(before int64, reason string) (int64, error) {
+	defer r.lock()()
+
+	now := time.Now().Unix()
+	var failed int64
+	for _, job := range r.state.jobs {
+		if job.Status == models.JobStatusRunning && job.UpdatedAt < before {
+			job.Status = models.JobStatusFailed
+			job.Error = reason
+			job.FinishedAt = &now
+			job.UpdatedAt = now
+			failed++
+		}
+	}
+	return failed, nil
+}
+
+// FindJob implements Repository
+func (r *MemoryRepository) FindJob(id uuid.UUID) (*models.Job, error) {
+	defer r.lock()()
+
+	for _, job := range r.state.jobs {
+		if job.ID == id {
+			found := *job
+			found.Payload = nil
+			return &found, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// CreateIdempotencyKey implements Repository
+func (r *MemoryRepository) CreateIdempotencyKey(key *models.IdempotencyKey) (bool, error) {
+	defer r.lock()()
+
+	if _, ok := r.state.idempotencyKeys[key.Key]; ok {
+		return false, nil
+	}
+	key.CreatedAt = time.Now().Unix()
+	stored := *key
+	r.state.idempotencyKeys[key.Key] = &stored
+	return true, nil
+}
+
+// FindIdempotencyKey implements Repository
+func (r *MemoryRepository) FindIdempotencyKey(key string) (*models.IdempotencyKey, error) {
+	defer r.lock()()
+
+	stored, ok := r.state.idempotencyKeys[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	found := *stored
+	return &found, nil
+}
+
+// SaveIdempotencyKey implements Repository
+func (r *MemoryRepository) SaveIdempotencyKey(key *models.IdempotencyKey) error {
+	defer r.lock()()
+
+	if stored, ok := r.state.idempotencyKeys[key.Key]; ok {
+		stored.Fingerprint = key.Fingerprint
+		stored.StatusCode = key.StatusCode
+		stored.Location = key.Location
+		stored.Response = key.Response
+	}
+	return nil
+}
+
+// DeleteIdempotencyKey implements Repository
+func (r *MemoryRepository) DeleteIdempotencyKey(key string) error {
+	defer r.lock()()
+
+	delete(r.state.idempotencyKeys, key)
+	return nil
+}
+
+// CreateRun implements Repository
+func (r *MemoryRepository) CreateRun(run *models.Run) error {
+	defer r.lock()()
+
+	if run.ID == uuid.Nil {
+		run.ID = uuid.New()
+	}
+	stored := *run
+	r.state.runs = append(r.state.runs, &stored)
+	return nil
+}
+
+// SaveRun implements Repository
+func (r *MemoryRepository) SaveRun(run *models.Run) error {
+	defer r.lock()()
+
+	for _, stored := range r.state.runs {
+		if stored.ID == run.ID {
+			startedAt := stored.StartedAt
+			*stored = *run
+			stored.StartedAt = startedAt
+			return nil
+		}
+	}
+	return nil
+}
+
+// LastRun implements Repository
+func (r *MemoryRepository) LastRun(status models.RunStatus) (*models.Run, error) {
+	defer r.lock()()
+
+	var last *models.Run
+	for _, run := range r.state.runs {
+		if run.Status == status && (last == nil || run.StartedAt >= last.StartedAt) {
+			last = run
+		}
+	}
+	if last == nil {
+		return nil, ErrNotFound
+	}
+	found := *last
+	return &found, nil
+}
+
+// TryLock implements Repository
+func (r *MemoryRepository) TryLock(id int64) (func(), bool, error) {
+	release, acquired := tryProcessLock(id)
+	return release, acquired, nil
+}
+
+// errDuplicateStudent mirrors the unique index on student_id of the database backends
+var errDuplicateStudent = errors.New("duplicate key value violates unique constraint on student_id")
+
+// clone returns a copy of the state that can be changed independently
+func (s *memoryState) clone() *memoryState {
+	clone := &memoryState{
+		students:        make(map[uuid.UUID]*models.Student, len(s.students)),
+		byStudentID:     make(map[string]uuid.UUID, len(s.byStudentID)),
+		evaluations:     append([]models.RiskEvaluation(nil), s.evaluations...),
+		jobs:            make([]*models.Job, len(s.jobs)),
+		idempotencyKeys: make(map[string]*models.IdempotencyKey, len(s.idempotencyKeys)),
+	}
+	for name, key := range s.idempotencyKeys {
+		copied := *key
+		clone.idempotencyKeys[name] = &copied
+	}
+	for _, run := range s.runs {
+		copied := *run
+		clone.runs = append(clone.runs, &copied)
+	}
+	for i, job := range s.jobs {
+		copied := *job
+		clone.jobs[i] = &copied
+	}
+	for id, student := range s.students {
+		clone.students[id] = copyStudent(student)
+	}
+	for studentID, id := range s.byStudentID {
+		clone.byStudentID[studentID] = id
+	}
+	return clone
+}
+
+// copyStudent returns a copy of student that shares no slices or pointers with it
+func copyStudent(student *models.Student) *models.Student {
+	copied := *student
+	copied.Attendance = append([]models.AttendanceRecord(nil), student.Attendance...)
+	copied.Assignments = append([]models.AssignmentRecord(nil), student.Assignments...)
+	copied.Contacts = append([]models.ContactRecord(nil), student.Contacts...)
+	copied.DropoutFactors = append(models.RiskFactors(nil), student.DropoutFactors...)
+	copied.DropoutScore = copyPtr(student.DropoutScore)
+	copied.DropoutRiskLevel = copyPtr(student.DropoutRiskLevel)
+	copied.DropoutNote = copyPtr(student.DropoutNote)
+	copied.AttendanceRate = copyPtr(student.AttendanceRate)
+	copied.AssignmentRate = copyPtr(student.AssignmentRate)
+	copied.ContactFailures = copyPtr(student.ContactFailures)
+	copied.InputHash = copyPtr(student.InputHash)
+	return &copied
+}
+
+// copyPtr returns a pointer to a copy of *p, or nil if p is nil
+func copyPtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// matchesFilter reports whether student is selected by filter, following the
+// SQL semantics of the database backends for students that were never evaluated
+func matchesFilter(student *models.Student, filter StudentFilter) bool {
+	if len(filter.RiskLevels) > 0 {
+		if student.DropoutRiskLevel == nil || !containsString(filter.RiskLevels, *student.DropoutRiskLevel) {
+			return false
+		}
+	}
+	if filter.MinScore != nil && (student.DropoutScore == nil || *student.DropoutScore < *filter.MinScore) {
+		return false
+	}
+	if filter.MaxScore != nil && (student.DropoutScore == nil || *student.DropoutScore > *filter.MaxScore) {
+		return false
+	}
+
+	if search := strings.ToLower(strings.TrimSpace(filter.Search)); search != "" {
+		if !strings.Contains(strings.ToLower(student.StudentID), search) &&
+			!strings.Contains(strings.ToLower(student.StudentName), search) {
+			return false
+		}
+	}
+
+	if filter.UpdatedSince != nil && student.UpdatedAt < *filter.UpdatedSince {
+		return false
+	}
+	if filter.DataUpdatedSince != nil && student.DataUpdatedAt < *filter.DataUpdatedSince {
+		return false
+	}
+
+	for _, name := range filter.Factors {
+		triggered := false
+		for _, factor := range student.DropoutFactors {
+			if factor.Name == name && factor.Triggered {
+				triggered = true
+				break
+			}
+		}
+		if !triggered {
+			return false
+		}
+	}
+	return true
+}
+
+// sortValues returns the sort key values of student
+func sortValues(keys []SortKey, student *models.Student) []interface{} {
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		values[i] = key.Value(student)
+	}
+	return values
+}
+
+// compareKeys compares two lists of sort key values, returning a negative
+// number when a sorts before b, a positive number when after and 0 when equal
+func compareKeys(keys []SortKey, a, b []interface{}) int {
+	for i, key := range keys {
+		c := compareValues(a[i], b[i])
+		if key.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareValues compares two int64 or two string sort key values
+func compareValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case int64:
+		b, _ := b.(int64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case string:
+		b, _ := b.(string)
+		return strings.Compare(a, b)
+	}
+	return 0
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"mindx/config"
+	"mindx/database"
+	"mindx/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned when a student, job or idempotency key does not exist
+var ErrNotFound = errors.New("record not found")
+
+// ErrJobNotRunning is returned when saving a job that is no longer running,
+// e.g. because it was marked as failed after its heartbeat stopped
+var ErrJobNotRunning = errors.New("job is no longer running")
+
+// Repository stores students, their activity records and risk evaluations.
+// Implementations must be safe for concurrent use.
+type Repository interface {
+	// Transaction runs fn with a Repository bound to a new transaction, committing
+	// when fn returns nil and rolling back otherwise. Calling Transaction on the
+	// Repository passed to fn nests a savepoint, so that a failing inner fn only
+	// rolls back its own changes.
+	Transaction(fn func(repo Repository) error) error
+
+	// FindStudent returns the student with the given student_id.
+	// It returns ErrNotFound if there is no such student.
+	FindStudent(studentID string, opts FindOptions) (*models.Student, error)
+	// FindStudents returns the students with the given student_ids that exist,
+	// in the order of studentIDs
+	FindStudents(studentIDs []string, opts FindOptions) ([]models.Student, error)
+	// ListStudents returns the students selected by opts and the number of
+	// students matching opts.Filter regardless of paging
+	ListStudents(opts ListOptions) ([]models.Student, int64, error)
+	// CreateStudent stores a new student without its activity records and sets its ID
+	CreateStudent(student *models.Student) error
+	// UpdateStudent renames the student with id, clears its input hash and restores it if it was deleted
+	UpdateStudent(id uuid.UUID, name string) error
+	// DeleteStudent soft-deletes the student with the given student_id.
+	// It returns ErrNotFound if there is no such student.
+	DeleteStudent(studentID string) error
+	// UpsertStudents stores students in bulk, keyed by student_id: new students are created,
+	// existing ones are renamed and restored if they were deleted, and the activity records of
+	// each are replaced by the ones it holds, along with its input hash. evaluations[i], the
+	// evaluation of students[i], is saved like SaveEvaluation. It sets the ID of every student
+	// and evaluation and reports for each student whether it was created or restored.
+	// Student IDs must be unique.
+	UpsertStudents(students []models.Student, evaluations []models.RiskEvaluation) ([]bool, error)
+
+	// ReplaceRecords replaces the activity records of the student with id by those held by records
+	// and clears its input hash
+	ReplaceRecords(id uuid.UUID, records *models.Student) error
+	// AddRecords stores the activity records held by records for the student with id
+	// and clears its input hash
+	AddRecords(id uuid.UUID, records *models.Student) error
+
+	// SaveEvaluation stores evaluation as the current risk of its student
+	// and appends it to the student's evaluation history
+	SaveEvaluation(evaluation *models.RiskEvaluation) error
+	// ListEvaluations returns the evaluation history of the student with id,
+	// or of every student when id is uuid.Nil, oldest first
+	ListEvaluations(id uuid.UUID) ([]models.RiskEvaluation, error)
+
+	// CreateJob stores a new job and sets its ID
+	CreateJob(job *models.Job) error
+	// ClaimJob marks the oldest queued job as running and returns it with its payload.
+	// It returns nil when no job is queued. Concurrent callers never claim the same job.
+	ClaimJob() (*models.Job, error)
+	// SaveJob stores the status, progress and outcome of job, leaving its payload unchanged.
+	// It only updates a job that is still running, and returns ErrJobNotRunning otherwise.
+	SaveJob(job *models.Job) error
+	// TouchJob records that the running job with id is still being processed
+	TouchJob(id uuid.UUID) error
+	// FailStaleJobs marks the running jobs last updated before the Unix time before
+	// as failed with reason, and returns how many it marked
+	FailStaleJobs(before int64, reason string) (int64, error)
+	// FindJob returns the job with id without its payload.
+	// It returns ErrNotFound if there is no such job.
+	FindJob(id uuid.UUID) (*models.Job, error)
+
+	// CreateIdempotencyKey stores key unless a key of the same name exists,
+	// reporting whether it was stored
+	CreateIdempotencyKey(key *models.IdempotencyKey) (bool, error)
+	// FindIdempotencyKey returns the idempotency key named key.
+	// It returns ErrNotFound if there is no such key.
+	FindIdempotencyKey(key string) (*models.IdempotencyKey, error)
+	// SaveIdempotencyKey stores the response recorded in key
+	SaveIdempotencyKey(key *models.IdempotencyKey) error
+	// DeleteIdempotencyKey removes the idempotency key named key, if any
+	DeleteIdempotencyKey(key string) error
+
+	// CreateRun stores a new re-evaluation run and sets its ID
+	CreateRun(run *models.Run) error
+	// SaveRun stores the status and counts of run
+	SaveRun(run *models.Run) error
+	// LastRun returns the most recently started run with status.
+	// It returns ErrNotFound if there is no such run.
+	LastRun(status models.RunStatus) (*models.Run, error)
+
+	// TryLock takes the lock with id unless it is held, reporting whether it
+	// did, and returns the function releasing it. Postgres takes an advisory
+	// lock, held across every process sharing the database; the other
+	// backends only lock within the process.
+	TryLock(id int64) (release func(), acquired bool, err error)
+}
+
+// FindOptions controls how FindStudent loads a student
+type FindOptions struct {
+	// IncludeDeleted also finds soft-deleted students
+	IncludeDeleted bool
+	// WithRecords loads the student's activity records
+	WithRecords bool
+	// ForUpdate locks the student until the surrounding transaction ends
+	ForUpdate bool
+}
+
+// StudentFilter selects students of the students list
+type StudentFilter struct {
+	// RiskLevels keeps students at any of these levels
+	RiskLevels []string
+	// MinScore and MaxScore bound the dropout score, inclusive
+	MinScore *int
+	MaxScore *int
+	// Search matches a case-insensitive substring of the student ID or name
+	Search string
+	// UpdatedSince keeps students updated at or after this Unix time
+	UpdatedSince *int64
+	// DataUpdatedSince keeps students whose name or records changed at or after
+	// this Unix time, ignoring evaluations saved since
+	DataUpdatedSince *int64
+	// Factors keeps students flagged for every one of these risk factors
+	Factors []string
+}
+
+// ListOptions selects, orders and pages the students list
+type ListOptions struct {
+	Filter StudentFilter
+	// Sort orders the students; it should end with a unique key such as student_id
+	Sort []SortKey
+	// After resumes the list after the student whose sort key values are After
+	After  []interface{}
+	Offset int
+	// Limit caps the number of students returned; 0 returns all of them
+	Limit int
+	// Fields names the student fields to load, by JSON name; empty loads every
+	// column. The activity records are only loaded when named.
+	Fields []string
+}
+
+// New creates the Repository selected by cfg.Driver, opening and
+// preparing its database when the driver has one
+func New(cfg config.DatabaseConfig) (Repository, error) {
+	switch cfg.Driver {
+	case config.DriverMemory:
+		return NewMemoryRepository(), nil
+	case "", config.DriverPostgres, config.DriverSQLite:
+		db, err := database.InitDB(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewGormRepository(db), nil
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+	}
+}
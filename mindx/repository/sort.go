@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"math"
+
+	"mindx/models"
+)
+
+// Sortable student fields
+const (
+	SortStudentID   = "student_id"
+	SortStudentName = "student_name"
+	SortCreatedAt   = "created_at"
+	SortUpdatedAt   = "updated_at"
+	SortScore       = "score"
+	SortRiskLevel   = "risk_level"
+)
+
+// SortKey is one field of a students sort order
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// Unevaluated students sort after every score and risk level when sorting
+// ascending and before them when sorting descending, so that they come last
+// in both directions.
+
+// unrankedRiskLevel returns the rank of students without a risk level.
+// Risk levels sort by models.RiskLevel.Rank.
+func unrankedRiskLevel(desc bool) int64 {
+	if desc {
+		return 0
+	}
+	return int64(len(models.RiskLevels) + 1)
+}
+
+// unscored returns the score students without a score sort as
+func unscored(desc bool) int64 {
+	if desc {
+		return math.MinInt32
+	}
+	return math.MaxInt32
+}
+
+// Value returns the key of student the list is ordered by: an int64 for
+// numeric fields and a string otherwise. Keyset cursors carry these values.
+func (k SortKey) Value(student *models.Student) interface{} {
+	switch k.Field {
+	case SortStudentName:
+		return student.StudentName
+	case SortCreatedAt:
+		return student.CreatedAt
+	case SortUpdatedAt:
+		return student.UpdatedAt
+	case SortScore:
+		if student.DropoutScore == nil {
+			return unscored(k.Desc)
+		}
+		return int64(*student.DropoutScore)
+	case SortRiskLevel:
+		if student.DropoutRiskLevel != nil {
+			if rank := models.RiskLevel(*student.DropoutRiskLevel).Rank(); rank > 0 {
+				return int64(rank)
+			}
+		}
+		return unrankedRiskLevel(k.Desc)
+	default:
+		return student.StudentID
+	}
+}
+
+// Numeric reports whether the key's values are int64
+func (k SortKey) Numeric() bool {
+	switch k.Field {
+	case SortCreatedAt, SortUpdatedAt, SortScore, SortRiskLevel:
+		return true
+	}
+	return false
+}
@@ -1,31 +1,45 @@
 package router
 
 import (
+	"mindx/config"
 	"mindx/handlers"
+	"mindx/repository"
+	"mindx/services"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
-	"gorm.io/gorm"
 )
 
 // InitRouter initializes the Echo router with middleware and routes
-func InitRouter(db *gorm.DB) *echo.Echo {
+func InitRouter(cfg *config.Config, repo repository.Repository, scorer services.RiskScorer, columns services.ColumnMapping, jobs *services.JobService) *echo.Echo {
 	e := echo.New()
 
 	// Middleware
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"http://localhost:5173"},
-		AllowMethods: []string{echo.GET, echo.POST, echo.PUT, echo.DELETE},
+		AllowOrigins:  []string{"http://localhost:5173"},
+		AllowMethods:  []string{echo.GET, echo.POST, echo.PUT, echo.PATCH, echo.DELETE},
+		ExposeHeaders: []string{"Idempotent-Replayed", "Link", "Location", "X-Total-Count"},
 	}))
 
 	// Initialize handlers
-	h := handlers.NewHandler(db)
+	h := handlers.NewHandler(cfg, repo, scorer, columns, jobs)
 
 	// Routes
-	e.POST("/evaluate", h.EvaluateRisk)
+	e.POST("/evaluate", h.EvaluateRisk, h.Idempotent)
+	e.POST("/evaluate/preview", h.PreviewEvaluation)
+	e.GET("/jobs/:id", h.GetJob)
 	e.GET("/students", h.ListStudents)
+	e.POST("/students", h.CreateStudent)
+	e.GET("/students/:student_id", h.GetStudent)
+	e.PUT("/students/:student_id", h.ReplaceStudent)
+	e.PATCH("/students/:student_id", h.PatchStudent)
+	e.DELETE("/students/:student_id", h.DeleteStudent)
+	e.GET("/students/:student_id/evaluations", h.ListStudentEvaluations)
+	e.POST("/students/:student_id/attendance", h.AppendAttendance)
+	e.POST("/students/:student_id/assignments", h.AppendAssignments)
+	e.POST("/students/:student_id/contacts", h.AppendContacts)
 
 	return e
 }
\ No newline at end of file
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"mindx/models"
+	"mindx/repository"
+)
+
+// ErrIdempotencyKeyReused is returned when an idempotency key is sent with a
+// request other than the one it was first used for
+var ErrIdempotencyKeyReused = errors.New("idempotency key was used for a different request")
+
+// ErrIdempotencyKeyInProgress is returned while the first request sent with
+// an idempotency key is still being processed
+var ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is in progress")
+
+// IdempotencyService stores the responses to requests sent with an idempotency key,
+// so that a repeated request gets the first response instead of being processed again
+type IdempotencyService struct {
+	repo repository.Repository
+	// ttl is how long the response to a completed request is remembered
+	ttl time.Duration
+	// lease is how long a key stays claimed by a request that has not completed,
+	// so that the key of a request lost to a crash can be used again
+	lease time.Duration
+}
+
+// NewIdempotencyService creates an IdempotencyService that remembers responses for
+// ttl and lets requests hold a key for up to lease
+func NewIdempotencyService(repo repository.Repository, ttl, lease time.Duration) *IdempotencyService {
+	return &IdempotencyService{repo: repo, ttl: ttl, lease: lease}
+}
+
+// Begin claims key for a request. It returns the stored key, with its response and
+// the fingerprint of its request, when a request with key already completed; the
+// caller checks with Matches that it is the same request before replaying the
+// response. Otherwise it returns nil and the caller must process the request and
+// then call Complete or Abandon.
+func (s *IdempotencyService) Begin(key string) (*models.IdempotencyKey, error) {
+	for {
+		created, err := s.repo.CreateIdempotencyKey(&models.IdempotencyKey{Key: key})
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			return nil, nil
+		}
+
+		stored, err := s.repo.FindIdempotencyKey(key)
+		if errors.Is(err, repository.ErrNotFound) {
+			// Abandoned in the meantime; claim it again
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Forget expired responses, and keys whose request never completed
+		expiry := s.ttl
+		if stored.StatusCode == 0 {
+			expiry = s.lease
+		}
+		if time.Since(time.Unix(stored.CreatedAt, 0)) > expiry {
+			if err := s.repo.DeleteIdempotencyKey(key); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if stored.StatusCode == 0 {
+			return nil, ErrIdempotencyKeyInProgress
+		}
+		return stored, nil
+	}
+}
+
+// Matches checks that the request identified by fingerprint is the one whose
+// response stored holds, returning ErrIdempotencyKeyReused otherwise
+func (s *IdempotencyService) Matches(stored *models.IdempotencyKey, fingerprint string) error {
+	if stored.Fingerprint != fingerprint {
+		return ErrIdempotencyKeyReused
+	}
+	return nil
+}
+
+// Complete stores the response to the request that claimed key, along with the
+// fingerprint identifying the request, known once its body has been read
+func (s *IdempotencyService) Complete(key, fingerprint string, statusCode int, location string, response []byte) error {
+	return s.repo.SaveIdempotencyKey(&models.IdempotencyKey{
+		Key:         key,
+		Fingerprint: fingerprint,
+		StatusCode:  statusCode,
+		Location:    location,
+		Response:    response,
+	})
+}
+
+// Abandon releases key after its request failed, so that it can be retried
+func (s *IdempotencyService) Abandon(key string) error {
+	return s.repo.DeleteIdempotencyKey(key)
+}
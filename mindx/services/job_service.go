@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"mindx/models"
+	"mindx/repository"
+
+	"github.com/google/uuid"
+)
+
+// jobPollInterval is how often idle workers look for queued jobs, so that jobs
+// queued by another process are picked up too
+const jobPollInterval = time.Second
+
+// jobHeartbeatInterval is how often a running job records that it is still
+// being processed. A running job without a heartbeat for jobStaleAfter was
+// interrupted, e.g. by a crash or restart, and is marked as failed.
+const (
+	jobHeartbeatInterval = 30 * time.Second
+	jobStaleAfter        = 3 * jobHeartbeatInterval
+)
+
+// jobInterrupted is the error of a job whose process stopped while running it
+const jobInterrupted = "job was interrupted; submit its data again"
+
+// JobResult summarises one student stored by a job
+type JobResult struct {
+	StudentID string `json:"student_id"`
+	// Action is "created", "updated" or "unchanged"
+	Action    string  `json:"action"`
+	RiskLevel *string `json:"risk_level"`
+	Score     *int    `json:"score"`
+}
+
+// JobService queues evaluation runs as jobs and processes them in the background
+type JobService struct {
+	repo     repository.Repository
+	students *StudentService
+	columns  ColumnMapping
+	// batchSize is the number of students committed together in partial mode
+	batchSize int
+	// wake signals idle workers that a job was queued
+	wake chan struct{}
+}
+
+// NewJobService creates a JobService that evaluates students with students,
+// reading CSV and XLSX data with columns
+func NewJobService(repo repository.Repository, students *StudentService, columns ColumnMapping, batchSize int) *JobService {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return &JobService{
+		repo:      repo,
+		students:  students,
+		columns:   columns,
+		batchSize: batchSize,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Start runs workers goroutines that process queued jobs until ctx is done.
+// Jobs left running by a process that stopped are not resumed: once their
+// heartbeat is older than jobStaleAfter they are marked as failed, on start
+// and then every jobHeartbeatInterval.
+func (s *JobService) Start(ctx context.Context, workers int) {
+	s.failStaleJobs()
+	go func() {
+		ticker := time.NewTicker(jobHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.failStaleJobs()
+			}
+		}
+	}()
+
+	for i := 0; i < workers; i++ {
+		go s.work(ctx)
+	}
+}
+
+// failStaleJobs marks the running jobs whose heartbeat stopped as failed
+func (s *JobService) failStaleJobs() {
+	failed, err := s.repo.FailStaleJobs(time.Now().Add(-jobStaleAfter).Unix(), jobInterrupted)
+	if err != nil {
+		log.Printf("Failed to fail interrupted jobs: %v", err)
+	} else if failed > 0 {
+		log.Printf("Marked %d interrupted jobs as failed", failed)
+	}
+}
+
+// Enqueue stores a queued job evaluating data in format with the atomic or partial mode
+func (s *JobService) Enqueue(mode, format string, data []byte) (*models.Job, error) {
+	job := &models.Job{
+		Status:  models.JobStatusQueued,
+		Mode:    mode,
+		Format:  format,
+		Payload: data,
+	}
+	if err := s.repo.CreateJob(job); err != nil {
+		return nil, err
+	}
+	job.Payload = nil
+
+	// Wake an idle worker without waiting for one
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// GetJob returns the job with id
+func (s *JobService) GetJob(id uuid.UUID) (*models.Job, error) {
+	return s.repo.FindJob(id)
+}
+
+// work claims and runs queued jobs one at a time
+func (s *JobService) work(ctx context.Context) {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		job, err := s.repo.ClaimJob()
+		if err != nil {
+			log.Printf("Failed to claim job: %v", err)
+		}
+		if job != nil {
+			s.run(job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// run processes a claimed job and stores its outcome
+func (s *JobService) run(job *models.Job) {
+	// Record a heartbeat until the job finishes
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(jobHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := s.repo.TouchJob(job.ID); err != nil {
+					log.Printf("Failed to record heartbeat of job %s: %v", job.ID, err)
+				}
+			}
+		}
+	}()
+
+	results := []JobResult{}
+	failures := []IngestionFailure{}
+	err := func() (err error) {
+		// A panicking job fails instead of stopping the worker
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("job panicked: %v", r)
+			}
+		}()
+		return s.process(job, &results, &failures)
+	}()
+
+	now := time.Now().Unix()
+	job.FinishedAt = &now
+	job.Status = models.JobStatusSucceeded
+	if err != nil {
+		job.Status = models.JobStatusFailed
+		job.Error = err.Error()
+	}
+	job.Failed = len(failures)
+	if job.Failures, err = marshalJobJSON(failures); err == nil {
+		job.Results, err = marshalJobJSON(results)
+	}
+	if err != nil {
+		job.Status = models.JobStatusFailed
+		job.Error = err.Error()
+	}
+
+	// The job may have been marked as failed meanwhile, if its heartbeat stopped
+	// for long enough; that outcome stands and this one is dropped
+	if err := s.repo.SaveJob(job); errors.Is(err, repository.ErrJobNotRunning) {
+		log.Printf("Lost the claim on job %s; discarding its outcome (%s)", job.ID, job.Status)
+	} else if err != nil {
+		log.Printf("Failed to save job %s: %v", job.ID, err)
+	}
+}
+
+// process decodes and evaluates the payload of job, collecting stored students in
+// results and rejected ones in failures. Progress is saved after every batch in
+// partial mode, which stops once the job is no longer running. Atomic jobs commit
+// every student in one transaction, so they only report progress once they finish.
+func (s *JobService) process(job *models.Job, results *[]JobResult, failures *[]IngestionFailure) error {
+	students, validationErrs, err := DecodeStudentsPartialAs(job.Format, job.Payload, s.columns)
+	if err != nil {
+		return err
+	}
+	rejected := validationErrs.Failures()
+	job.Total = len(students) + len(rejected)
+	record := func(student models.Student, outcome string) {
+		switch outcome {
+		case OutcomeCreated:
+			job.Created++
+		case OutcomeUpdated:
+			job.Updated++
+		default:
+			job.Unchanged++
+		}
+		job.Processed++
+		*results = append(*results, JobResult{
+			StudentID: student.StudentID,
+			Action:    outcome,
+			RiskLevel: student.DropoutRiskLevel,
+			Score:     student.DropoutScore,
+		})
+	}
+
+	if job.Mode != models.JobModePartial {
+		// Atomic jobs store nothing unless every student is valid and stored
+		if len(rejected) > 0 {
+			*failures = rejected
+			return errors.New("invalid student records")
+		}
+		err := s.students.processStudents(students, record)
+		if err != nil {
+			*results = []JobResult{}
+			job.Created, job.Updated, job.Unchanged, job.Processed = 0, 0, 0, 0
+		}
+		return err
+	}
+
+	*failures = rejected
+	job.Processed = len(rejected)
+	for start := 0; start < len(students); start += s.batchSize {
+		end := start + s.batchSize
+		if end > len(students) {
+			end = len(students)
+		}
+		report, err := s.students.ProcessAndEvaluateStudentsPartial(students[start:end])
+		if err != nil {
+			return err
+		}
+		for _, student := range report.Created {
+			record(student, OutcomeCreated)
+		}
+		for _, student := range report.Updated {
+			record(student, OutcomeUpdated)
+		}
+		for _, student := range report.Unchanged {
+			record(student, OutcomeUnchanged)
+		}
+		*failures = append(*failures, report.Failed...)
+		job.Processed += len(report.Failed)
+		job.Failed = len(*failures)
+
+		if err := s.repo.SaveJob(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// marshalJobJSON encodes a job's failures or results
+func marshalJobJSON(v interface{}) (models.JSON, error) {
+	data, err := json.Marshal(v)
+	return models.JSON(data), err
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"mindx/config"
+	"mindx/models"
+	"mindx/repository"
+)
+
+// Level changes reported by an evaluation preview
+const (
+	ChangeUpgrade   = "upgrade"
+	ChangeDowngrade = "downgrade"
+	ChangeNone      = "none"
+	// ChangeUnevaluated is a student without a stored risk level
+	ChangeUnevaluated = "unevaluated"
+)
+
+// PreviewStudent compares the stored evaluation of a student with the one it gets in a preview
+type PreviewStudent struct {
+	StudentID    string  `json:"student_id"`
+	StudentName  string  `json:"student_name"`
+	CurrentLevel *string `json:"current_level"`
+	CurrentScore *int    `json:"current_score"`
+	PreviewLevel string  `json:"preview_level"`
+	PreviewScore int     `json:"preview_score"`
+	PreviewNote  string  `json:"preview_note"`
+	// Change is "upgrade", "downgrade", "none" or "unevaluated"
+	Change string `json:"change"`
+}
+
+// EvaluationPreview reports how the risk levels of the stored students would
+// change with another scoring configuration
+type EvaluationPreview struct {
+	Total       int `json:"total"`
+	Upgraded    int `json:"upgraded"`
+	Downgraded  int `json:"downgraded"`
+	Unchanged   int `json:"unchanged"`
+	Unevaluated int `json:"unevaluated"`
+	// CurrentLevels and PreviewLevels count the students at each level
+	CurrentLevels map[string]int `json:"current_levels"`
+	PreviewLevels map[string]int `json:"preview_levels"`
+	// Transitions counts the students changing level by current and preview level
+	Transitions map[string]map[string]int `json:"transitions"`
+	Students    []PreviewStudent          `json:"students"`
+}
+
+// NewPreviewScorer creates the scorer of an evaluation preview: base with the
+// fields set in overrides, a JSON object named like the RISK_* settings, scoring
+// with rules instead of the rules file when rules is not nil. It returns an
+// error for unknown or invalid settings and invalid rules.
+func NewPreviewScorer(base config.RiskConfig, overrides json.RawMessage, rules *RuleFile) (RiskScorer, error) {
+	cfg := base
+	if len(overrides) > 0 && !bytes.Equal(overrides, []byte("null")) {
+		dec := json.NewDecoder(bytes.NewReader(overrides))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("invalid risk settings: %w", err)
+		}
+	}
+
+	if rules == nil {
+		return NewRiskScorer(&cfg)
+	}
+	set, err := CompileRuleFile(*rules, cfg.MediumRiskThreshold, cfg.HighRiskThreshold)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules: %w", err)
+	}
+	cfg.ScoringMode = config.ScoringModeRules
+	return NewRulesScorerWithRules(&cfg, set), nil
+}
+
+// PreviewEvaluations scores every stored student with scorer and compares the
+// risk level it gets with the stored one, without writing anything. Only the
+// students whose level changes are listed when changedOnly is set; the counts
+// always cover every student.
+func (s *StudentService) PreviewEvaluations(scorer RiskScorer, changedOnly bool) (*EvaluationPreview, error) {
+	preview := &EvaluationPreview{
+		CurrentLevels: map[string]int{},
+		PreviewLevels: map[string]int{},
+		Transitions:   map[string]map[string]int{},
+		Students:      []PreviewStudent{},
+	}
+
+	previewer := s.withScorer(scorer)
+	err := previewer.scoreStoredStudents(repository.StudentFilter{}, func(students []models.Student, evaluations []models.RiskEvaluation) error {
+		for i := range students {
+			student, evaluation := &students[i], &evaluations[i]
+			result := PreviewStudent{
+				StudentID:    student.StudentID,
+				StudentName:  student.StudentName,
+				CurrentLevel: student.DropoutRiskLevel,
+				CurrentScore: student.DropoutScore,
+				PreviewLevel: string(evaluation.RiskLevel),
+				PreviewScore: evaluation.Score,
+				PreviewNote:  evaluation.Note,
+				Change:       levelChange(student.DropoutRiskLevel, evaluation.RiskLevel),
+			}
+
+			preview.Total++
+			preview.PreviewLevels[result.PreviewLevel]++
+			switch result.Change {
+			case ChangeUpgrade:
+				preview.Upgraded++
+			case ChangeDowngrade:
+				preview.Downgraded++
+			case ChangeNone:
+				preview.Unchanged++
+			default:
+				preview.Unevaluated++
+			}
+			if result.CurrentLevel != nil {
+				preview.CurrentLevels[*result.CurrentLevel]++
+				if result.Change != ChangeNone {
+					if preview.Transitions[*result.CurrentLevel] == nil {
+						preview.Transitions[*result.CurrentLevel] = map[string]int{}
+					}
+					preview.Transitions[*result.CurrentLevel][result.PreviewLevel]++
+				}
+			}
+
+			if !changedOnly || result.Change != ChangeNone {
+				preview.Students = append(preview.Students, result)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+// withScorer returns a copy of the service that evaluates with scorer
+func (s *StudentService) withScorer(scorer RiskScorer) *StudentService {
+	copied := *s
+	copied.scorer = scorer
+	return &copied
+}
+
+// levelChange compares the stored risk level of a student with a new one
+func levelChange(current *string, level models.RiskLevel) string {
+	if current == nil {
+		return ChangeUnevaluated
+	}
+	from, to := models.RiskLevel(*current).Rank(), level.Rank()
+	switch {
+	case to > from:
+		return ChangeUpgrade
+	case to < from:
+		return ChangeDowngrade
+	default:
+		return ChangeNone
+	}
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"mindx/config"
+	"mindx/models"
+)
+
+// RateWindow restricts and weights dated records when computing attendance
+// and assignment rates, so that recent disengagement is not hidden by a good
+// term average. The zero value counts every record equally.
+type RateWindow struct {
+	// Days keeps only records dated within this many days before the as-of date
+	Days int
+	// Sessions keeps only the latest this many records
+	Sessions int
+	// HalfLifeDays weights each record by 0.5^(age in days / HalfLifeDays)
+	HalfLifeDays float64
+	// AsOf is the default reference date; the zero value means today
+	AsOf time.Time
+}
+
+// NewRateWindow creates a RateWindow from the risk configuration.
+// An unparseable AsOf date is ignored in favour of today.
+func NewRateWindow(cfg *config.RiskConfig) RateWindow {
+	window := RateWindow{
+		Days:         cfg.WindowDays,
+		Sessions:     cfg.WindowSessions,
+		HalfLifeDays: cfg.RecencyHalfLifeDays,
+	}
+	if cfg.AsOf != "" {
+		if asOf, err := time.Parse(models.DateLayout, cfg.AsOf); err == nil {
+			window.AsOf = asOf
+		}
+	}
+	return window
+}
+
+// enabled reports whether the window filters or weights records at all
+func (w RateWindow) enabled() bool {
+	return w.Days > 0 || w.Sessions > 0 || w.HalfLifeDays > 0
+}
+
+// referenceDate returns asOf, or the window's own as-of date, or today
+func (w RateWindow) referenceDate(asOf time.Time) time.Time {
+	if asOf.IsZero() {
+		asOf = w.AsOf
+	}
+	if asOf.IsZero() {
+		asOf = time.Now().UTC()
+	}
+	return asOf.Truncate(24 * time.Hour)
+}
+
+// AttendanceRate calculates the windowed attendance rate as a percentage
+func (w RateWindow) AttendanceRate(attendance []models.AttendanceRecord, asOf time.Time) float64 {
+	if !w.enabled() {
+		return calculateAttendanceRate(attendance)
+	}
+
+	events := make([]datedEvent, 0, len(attendance))
+	for _, a := range attendance {
+		events = append(events, datedEvent{date: a.Date, positive: a.Status == models.AttendanceStatusAttend})
+	}
+	return w.rate(events, w.referenceDate(asOf))
+}
+
+// AssignmentRate calculates the windowed assignment completion rate as a percentage
+func (w RateWindow) AssignmentRate(assignments []models.AssignmentRecord, asOf time.Time) float64 {
+	if !w.enabled() {
+		return calculateAssignmentRate(assignments)
+	}
+
+	events := make([]datedEvent, 0, len(assignments))
+	for _, a := range assignments {
+		events = append(events, datedEvent{date: a.Date, positive: a.Submitted})
+	}
+	return w.rate(events, w.referenceDate(asOf))
+}
+
+// datedEvent is a dated yes/no outcome such as attending or submitting
+type datedEvent struct {
+	date     string
+	positive bool
+	parsed   time.Time
+}
+
+// rate returns the weighted percentage of positive events inside the window.
+// Events with unparseable dates or dated after asOf are ignored, and an empty
+// window counts as 100% like an empty record list does.
+func (w RateWindow) rate(events []datedEvent, asOf time.Time) float64 {
+	var inWindow []datedEvent
+	for _, e := range events {
+		parsed, err := time.Parse(models.DateLayout, e.date)
+		if err != nil || parsed.After(asOf) {
+			continue
+		}
+		if w.Days > 0 && asOf.Sub(parsed) >= time.Duration(w.Days)*24*time.Hour {
+			continue
+		}
+		e.parsed = parsed
+		inWindow = append(inWindow, e)
+	}
+
+	if w.Sessions > 0 && len(inWindow) > w.Sessions {
+		sort.SliceStable(inWindow, func(i, j int) bool {
+			return inWindow[i].parsed.After(inWindow[j].parsed)
+		})
+		inWindow = inWindow[:w.Sessions]
+	}
+
+	var total, positive float64
+	for _, e := range inWindow {
+		weight := 1.0
+		if w.HalfLifeDays > 0 {
+			ageDays := asOf.Sub(e.parsed).Hours() / 24
+			weight = math.Pow(0.5, ageDays/w.HalfLifeDays)
+		}
+		total += weight
+		if e.positive {
+			positive += weight
+		}
+	}
+
+	if total == 0 {
+		return 100.0
+	}
+	return positive / total * 100.0
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"mindx/config"
+	"mindx/models"
+	"mindx/repository"
+
+	"github.com/robfig/cron/v3"
+)
+
+// reevaluationLockID is the lock held while a re-evaluation runs, so that
+// replicas sharing the database never run one at the same time
+const reevaluationLockID int64 = 7_364_786_902
+
+// ReevaluationReport counts the students of a re-evaluation
+type ReevaluationReport struct {
+	// Total is the number of students re-evaluated, of which Updated got a
+	// new evaluation and Unchanged kept the one they had
+	Total     int
+	Updated   int
+	Unchanged int
+}
+
+// ReevaluateStudents scores the stored students matching filter again from their
+// stored records and saves the evaluations that changed. Students are read and
+// committed in batches; when a batch fails, the batches committed so far stay
+// stored and the report counts them.
+func (s *StudentService) ReevaluateStudents(filter repository.StudentFilter) (*ReevaluationReport, error) {
+	report := &ReevaluationReport{}
+	err := s.scoreStoredStudents(filter, func(students []models.Student, evaluations []models.RiskEvaluation) error {
+		updated := 0
+		err := s.repo.Transaction(func(tx repository.Repository) error {
+			for i := range students {
+				if hasEvaluation(&students[i], &evaluations[i]) {
+					continue
+				}
+				if err := tx.SaveEvaluation(&evaluations[i]); err != nil {
+					return err
+				}
+				updated++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		report.Total += len(students)
+		report.Updated += updated
+		report.Unchanged += len(students) - updated
+		return nil
+	})
+	return report, err
+}
+
+// scoreStoredStudents loads the stored students matching filter with their records
+// in batches ordered by student_id, scores each batch and passes it to fn
+func (s *StudentService) scoreStoredStudents(filter repository.StudentFilter, fn func(students []models.Student, evaluations []models.RiskEvaluation) error) error {
+	sort := []repository.SortKey{{Field: repository.SortStudentID}}
+	var after []interface{}
+	for {
+		page, _, err := s.repo.ListStudents(repository.ListOptions{
+			Filter: filter,
+			Sort:   sort,
+			After:  after,
+			Limit:  s.batchSize,
+			Fields: []string{"student_id"},
+		})
+		if err != nil {
+			return err
+		}
+		if len(page) == 0 {
+			return nil
+		}
+		after = []interface{}{sort[0].Value(&page[len(page)-1])}
+
+		studentIDs := make([]string, len(page))
+		for i := range page {
+			studentIDs[i] = page[i].StudentID
+		}
+		students, err := s.repo.FindStudents(studentIDs, repository.FindOptions{WithRecords: true})
+		if err != nil {
+			return err
+		}
+		if err := fn(students, s.scoreStudents(students)); err != nil {
+			return err
+		}
+	}
+}
+
+// ReevaluationService re-evaluates the stored students on a schedule, so that
+// evaluations follow changes of the scoring configuration and of the date windows
+type ReevaluationService struct {
+	repo     repository.Repository
+	students *StudentService
+	// scope is config.ScheduleScopeAll or config.ScheduleScopeChanged
+	scope string
+}
+
+// NewReevaluationService creates a ReevaluationService re-evaluating the students
+// in scope with students. It returns an error for an unknown scope.
+func NewReevaluationService(repo repository.Repository, students *StudentService, scope string) (*ReevaluationService, error) {
+	switch scope {
+	case config.ScheduleScopeAll, config.ScheduleScopeChanged:
+	default:
+		return nil, fmt.Errorf("unknown re-evaluation scope %q", scope)
+	}
+	return &ReevaluationService{repo: repo, students: students, scope: scope}, nil
+}
+
+// Schedule runs re-evaluations on the cron schedule spec until ctx is done.
+// It returns an error if spec is not a valid cron expression.
+func (s *ReevaluationService) Schedule(ctx context.Context, spec string) error {
+	scheduler := cron.New()
+	_, err := scheduler.AddFunc(spec, func() {
+		run, err := s.Run()
+		switch {
+		case err != nil:
+			log.Printf("Re-evaluation failed: %v", err)
+		case run == nil:
+			log.Printf("Re-evaluation skipped: another run is in progress")
+		default:
+			log.Printf("Re-evaluated %d students: %d updated, %d unchanged", run.Total, run.Updated, run.Unchanged)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+
+	scheduler.Start()
+	go func() {
+		<-ctx.Done()
+		scheduler.Stop()
+	}()
+	return nil
+}
+
+// Run re-evaluates the students in scope and records the run in the runs table.
+// It returns nil without running while another run holds the re-evaluation lock,
+// in this process or, on Postgres, in any process sharing the database.
+func (s *ReevaluationService) Run() (*models.Run, error) {
+	release, acquired, err := s.repo.TryLock(reevaluationLockID)
+	if err != nil || !acquired {
+		return nil, err
+	}
+	defer release()
+
+	run := &models.Run{
+		Status:    models.RunStatusRunning,
+		Scope:     s.scope,
+		StartedAt: time.Now().Unix(),
+	}
+	var filter repository.StudentFilter
+	if s.scope == config.ScheduleScopeChanged {
+		// Only students whose name or records changed since the start of the last
+		// successful run; the evaluations that run saved do not count as changes
+		last, err := s.repo.LastRun(models.RunStatusSucceeded)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+		if last != nil {
+			run.Since = &last.StartedAt
+			filter.DataUpdatedSince = run.Since
+		}
+	}
+	if err := s.repo.CreateRun(run); err != nil {
+		return nil, err
+	}
+
+	err = func() (err error) {
+		// A panicking run fails instead of stopping the scheduler
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("run panicked: %v", r)
+			}
+		}()
+		report, err := s.students.ReevaluateStudents(filter)
+		run.Total, run.Updated, run.Unchanged = report.Total, report.Updated, report.Unchanged
+		return err
+	}()
+
+	now := time.Now().Unix()
+	run.FinishedAt = &now
+	run.Status = models.RunStatusSucceeded
+	if err != nil {
+		run.Status = models.RunStatusFailed
+		run.Error = err.Error()
+	}
+	if saveErr := s.repo.SaveRun(run); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	return run, err
+}
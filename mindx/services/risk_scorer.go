@@ -0,0 +1,240 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"mindx/config"
+	"mindx/models"
+)
+
+// RiskInput holds the parsed activity records a RiskScorer evaluates
+type RiskInput struct {
+	Attendance  []models.AttendanceRecord
+	Assignments []models.AssignmentRecord
+	Contacts    []models.ContactRecord
+	// AsOf is the reference date for time windows; the zero value uses the configured date
+	AsOf time.Time
+}
+
+// RiskScorer computes a risk evaluation from a student's activity records.
+// Implementations must be safe for concurrent use.
+type RiskScorer interface {
+	Score(input RiskInput) models.RiskEvaluation
+}
+
+// NewRiskInput collects the activity records of a student into a RiskInput
+func NewRiskInput(student *models.Student) RiskInput {
+	return RiskInput{
+		Attendance:  student.Attendance,
+		Assignments: student.Assignments,
+		Contacts:    student.Contacts,
+	}
+}
+
+// EvaluateStudent scores a student with the given scorer and links the evaluation to it
+func EvaluateStudent(scorer RiskScorer, student *models.Student) models.RiskEvaluation {
+	evaluation := scorer.Score(NewRiskInput(student))
+	evaluation.StudentID = student.ID
+	return evaluation
+}
+
+// NewRiskScorer creates the RiskScorer selected by cfg.ScoringMode.
+// It returns an error for an unknown mode or an invalid rules file.
+func NewRiskScorer(cfg *config.RiskConfig) (RiskScorer, error) {
+	switch cfg.ScoringMode {
+	case "", config.ScoringModeCount:
+		return NewRuleBasedScorer(cfg), nil
+	case config.ScoringModeWeighted:
+		return NewWeightedScorer(cfg), nil
+	case config.ScoringModeRules:
+		return NewRulesScorer(cfg)
+	default:
+		return nil, fmt.Errorf("unknown risk scoring mode %q", cfg.ScoringMode)
+	}
+}
+
+// RuleBasedScorer is the default RiskScorer. It flags attendance, assignment
+// and communication risk against the configured thresholds and scores one
+// point per flagged factor.
+type RuleBasedScorer struct {
+	config *config.RiskConfig
+	window RateWindow
+	trend  TrendDetector
+}
+
+// NewRuleBasedScorer creates a new RuleBasedScorer instance
+func NewRuleBasedScorer(cfg *config.RiskConfig) *RuleBasedScorer {
+	return &RuleBasedScorer{config: cfg, window: NewRateWindow(cfg), trend: NewTrendDetector(cfg)}
+}
+
+// Score implements RiskScorer
+func (r *RuleBasedScorer) Score(input RiskInput) models.RiskEvaluation {
+	// Calculate risk factors
+	var riskFactors []string
+	var factors models.RiskFactors
+	score := 0
+	flag := func(triggered bool) float64 {
+		if triggered {
+			score++
+			return 1
+		}
+		return 0
+	}
+
+	// Attendance risk
+	attendanceRate := r.window.AttendanceRate(input.Attendance, input.AsOf)
+	attendanceRisk := attendanceRate < r.config.AttendanceThreshold
+	if attendanceRisk {
+		riskFactors = append(riskFactors, "attendance")
+	}
+	factors = append(factors, rateFactor("attendance", attendanceRate, r.config.AttendanceThreshold, 1, flag(attendanceRisk)))
+
+	// Assignment risk
+	assignmentRate := r.window.AssignmentRate(input.Assignments, input.AsOf)
+	assignmentRisk := assignmentRate < r.config.AssignmentThreshold
+	if assignmentRisk {
+		riskFactors = append(riskFactors, "assignment")
+	}
+	factors = append(factors, rateFactor("assignment", assignmentRate, r.config.AssignmentThreshold, 1, flag(assignmentRisk)))
+
+	// Contact risk
+	contactFailures := countContactFailures(input.Contacts)
+	contactRisk := contactFailures >= r.config.ContactThreshold
+	if contactRisk {
+		riskFactors = append(riskFactors, "communication")
+	}
+	factors = append(factors, countFactor("communication", contactFailures, r.config.ContactThreshold, 1, flag(contactRisk)))
+
+	// Declining engagement, when the trend factor is enabled
+	var trend EngagementTrend
+	if r.trend.Enabled() {
+		trend = r.trend.Detect(input)
+		if trend.Detected() {
+			riskFactors = append(riskFactors, "engagement trend")
+		}
+		factors = append(factors, trendFactor(trend, 1, flag(trend.Detected())))
+	}
+
+	return models.RiskEvaluation{
+		Score:               score,
+		RiskLevel:           r.riskLevel(score),
+		Note:                riskNote(riskFactors, trend.Details),
+		Factors:             factors,
+		AttendanceRate:      attendanceRate,
+		AssignmentRate:      assignmentRate,
+		ContactFailures:     contactFailures,
+		ScoringMode:         config.ScoringModeCount,
+		AttendanceThreshold: r.config.AttendanceThreshold,
+		AssignmentThreshold: r.config.AssignmentThreshold,
+		ContactThreshold:    r.config.ContactThreshold,
+		MediumRiskThreshold: r.config.MediumRiskThreshold,
+		HighRiskThreshold:   r.config.HighRiskThreshold,
+	}
+}
+
+// riskLevel maps a score to a risk level using the configured thresholds
+func (r *RuleBasedScorer) riskLevel(score int) models.RiskLevel {
+	switch {
+	case score >= r.config.HighRiskThreshold:
+		return models.RiskLevelHigh
+	case score >= r.config.MediumRiskThreshold:
+		return models.RiskLevelMedium
+	default:
+		return models.RiskLevelLow
+	}
+}
+
+// riskNote builds the human-readable note for a list of flagged factors,
+// followed by any trend details in parentheses
+func riskNote(riskFactors []string, details []string) string {
+	if len(riskFactors) == 0 {
+		return "No signs of disengagement detected"
+	}
+	note := strings.Join(riskFactors, ", ") + " risk factors"
+	if len(details) > 0 {
+		note += " (" + strings.Join(details, "; ") + ")"
+	}
+	return note
+}
+
+// rateFactor describes a percentage rate that is risky below threshold
+func rateFactor(name string, rate, threshold, weight, contribution float64) models.RiskFactor {
+	return models.RiskFactor{
+		Name:         name,
+		Value:        &rate,
+		Unit:         "%",
+		Threshold:    &threshold,
+		Weight:       weight,
+		Contribution: contribution,
+		Triggered:    contribution > 0,
+	}
+}
+
+// countFactor describes a count that is risky at or above threshold
+func countFactor(name string, count, threshold int, weight, contribution float64) models.RiskFactor {
+	value, limit := float64(count), float64(threshold)
+	return models.RiskFactor{
+		Name:         name,
+		Value:        &value,
+		Threshold:    &limit,
+		Weight:       weight,
+		Contribution: contribution,
+		Triggered:    contribution > 0,
+	}
+}
+
+// trendFactor describes the engagement trend, detailing each declining signal
+func trendFactor(trend EngagementTrend, weight, contribution float64) models.RiskFactor {
+	return models.RiskFactor{
+		Name:         "engagement trend",
+		Weight:       weight,
+		Contribution: contribution,
+		Triggered:    trend.Detected(),
+		Detail:       strings.Join(trend.Details, "; "),
+	}
+}
+
+// calculateAttendanceRate calculates the attendance rate as a percentage
+func calculateAttendanceRate(attendance []models.AttendanceRecord) float64 {
+	if len(attendance) == 0 {
+		return 100.0
+	}
+
+	attended := 0
+	for _, a := range attendance {
+		if a.Status == models.AttendanceStatusAttend {
+			attended++
+		}
+	}
+
+	return float64(attended) / float64(len(attendance)) * 100.0
+}
+
+// calculateAssignmentRate calculates the assignment completion rate as a percentage
+func calculateAssignmentRate(assignments []models.AssignmentRecord) float64 {
+	if len(assignments) == 0 {
+		return 100.0
+	}
+
+	submitted := 0
+	for _, a := range assignments {
+		if a.Submitted {
+			submitted++
+		}
+	}
+
+	return float64(submitted) / float64(len(assignments)) * 100.0
+}
+
+// countContactFailures counts the number of failed contact attempts
+func countContactFailures(contacts []models.ContactRecord) int {
+	failures := 0
+	for _, c := range contacts {
+		if c.Status == models.ContactStatusFailed {
+			failures++
+		}
+	}
+	return failures
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Variables available to risk rules
+var ruleVariables = map[string]bool{
+	"attendance_rate":    true,
+	"assignment_rate":    true,
+	"contact_failures":   true,
+	"absences":           true,
+	"missed_assignments": true,
+	"trend":              true,
+}
+
+// RuleFile is the on-disk shape of a rules file (YAML or JSON)
+type RuleFile struct {
+	Levels struct {
+		Medium *int `json:"medium" yaml:"medium"`
+		High   *int `json:"high" yaml:"high"`
+	} `json:"levels" yaml:"levels"`
+	Rules []string `json:"rules" yaml:"rules"`
+}
+
+// RuleSet is a compiled, validated set of risk rules
+type RuleSet struct {
+	Rules  []Rule
+	Medium int
+	High   int
+}
+
+// Rule adds Points to the score and reports Factor when its condition holds.
+// Source is the rule text as written in the rules file.
+type Rule struct {
+	Source    string
+	Factor    string
+	Points    int
+	condition ruleExpr
+	variables map[string]bool
+}
+
+// ruleExpr is a node of a compiled rule condition
+type ruleExpr interface {
+	eval(vars map[string]float64) bool
+}
+
+type andExpr struct{ left, right ruleExpr }
+type orExpr struct{ left, right ruleExpr }
+type notExpr struct{ inner ruleExpr }
+type compareExpr struct {
+	variable string
+	op       string
+	value    float64
+}
+
+func (e andExpr) eval(vars map[string]float64) bool { return e.left.eval(vars) && e.right.eval(vars) }
+func (e orExpr) eval(vars map[string]float64) bool  { return e.left.eval(vars) || e.right.eval(vars) }
+func (e notExpr) eval(vars map[string]float64) bool { return !e.inner.eval(vars) }
+
+func (e compareExpr) eval(vars map[string]float64) bool {
+	v := vars[e.variable]
+	switch e.op {
+	case "<":
+		return v < e.value
+	case "<=":
+		return v <= e.value
+	case ">":
+		return v > e.value
+	case ">=":
+		return v >= e.value
+	case "==":
+		return v == e.value
+	case "!=":
+		return v != e.value
+	}
+	return false
+}
+
+// LoadRuleSet reads and compiles a rules file. Files ending in .json are
+// decoded as JSON, anything else as YAML. Levels missing from the file
+// default to medium and high.
+func LoadRuleSet(path string, medium, high int) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file RuleFile
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &file)
+	} else {
+		err = yaml.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules file %s: %w", path, err)
+	}
+
+	return CompileRuleFile(file, medium, high)
+}
+
+// CompileRuleFile validates and compiles every rule of file
+func CompileRuleFile(file RuleFile, medium, high int) (*RuleSet, error) {
+	if len(file.Rules) == 0 {
+		return nil, fmt.Errorf("rules file defines no rules")
+	}
+
+	set := &RuleSet{Medium: medium, High: high}
+	if file.Levels.Medium != nil {
+		set.Medium = *file.Levels.Medium
+	}
+	if file.Levels.High != nil {
+		set.High = *file.Levels.High
+	}
+	if set.High < set.Medium {
+		return nil, fmt.Errorf("high level %d is below medium level %d", set.High, set.Medium)
+	}
+
+	for i, source := range file.Rules {
+		rule, err := ParseRule(source)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d %q: %w", i+1, source, err)
+		}
+		set.Rules = append(set.Rules, rule)
+	}
+	return set, nil
+}
+
+// ParseRule compiles a rule of the form
+//
+//	attendance_rate < 60 AND contact_failures >= 1 => +2 "disengaged"
+//
+// Conditions compare a variable with a number using <, <=, >, >=, == or !=
+// and combine comparisons with AND, OR, NOT and parentheses. The action adds
+// (or with -N subtracts) points and names the factor reported in the note;
+// without a name the condition itself is reported.
+func ParseRule(source string) (Rule, error) {
+	parts := strings.Split(source, "=>")
+	if len(parts) != 2 {
+		return Rule{}, fmt.Errorf("expected exactly one \"=>\"")
+	}
+
+	tokens, err := tokenizeRule(parts[0])
+	if err != nil {
+		return Rule{}, err
+	}
+	p := &ruleParser{tokens: tokens, variables: make(map[string]bool)}
+	condition, err := p.parseOr()
+	if err != nil {
+		return Rule{}, err
+	}
+	if !p.done() {
+		return Rule{}, fmt.Errorf("unexpected %q", p.peek())
+	}
+
+	points, factor, err := parseRuleAction(parts[1])
+	if err != nil {
+		return Rule{}, err
+	}
+	if factor == "" {
+		factor = strings.TrimSpace(parts[0])
+	}
+
+	return Rule{
+		Source:    strings.TrimSpace(source),
+		Factor:    factor,
+		Points:    points,
+		condition: condition,
+		variables: p.variables,
+	}, nil
+}
+
+// parseRuleAction parses the `+N "factor"` part of a rule
+func parseRuleAction(action string) (int, string, error) {
+	action = strings.TrimSpace(action)
+	end := strings.IndexFunc(action, unicode.IsSpace)
+	if end < 0 {
+		end = len(action)
+	}
+
+	pointsText := action[:end]
+	if !strings.HasPrefix(pointsText, "+") && !strings.HasPrefix(pointsText, "-") {
+		return 0, "", fmt.Errorf("action must start with +N or -N, got %q", pointsText)
+	}
+	points, err := strconv.Atoi(pointsText)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid points %q", pointsText)
+	}
+
+	rest := strings.TrimSpace(action[end:])
+	if rest == "" {
+		return points, "", nil
+	}
+	factor, err := strconv.Unquote(rest)
+	if err != nil {
+		return 0, "", fmt.Errorf("factor name must be a quoted string, got %s", rest)
+	}
+	return points, factor, nil
+}
+
+// tokenizeRule splits a rule condition into identifiers, numbers, operators and parentheses
+func tokenizeRule(input string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(input); {
+		ch := rune(input[i])
+		switch {
+		case unicode.IsSpace(ch):
+			i++
+		case ch == '(' || ch == ')':
+			tokens = append(tokens, string(ch))
+			i++
+		case strings.ContainsRune("<>=!", ch):
+			if i+1 < len(input) && input[i+1] == '=' {
+				tokens = append(tokens, input[i:i+2])
+				i += 2
+			} else if ch == '<' || ch == '>' {
+				tokens = append(tokens, string(ch))
+				i++
+			} else {
+				return nil, fmt.Errorf("invalid operator at %q", input[i:])
+			}
+		case unicode.IsLetter(ch) || ch == '_' || unicode.IsDigit(ch) || ch == '.' || ch == '-':
+			start := i
+			for i < len(input) && (unicode.IsLetter(rune(input[i])) || unicode.IsDigit(rune(input[i])) ||
+				strings.ContainsRune("_.-", rune(input[i]))) {
+				i++
+			}
+			tokens = append(tokens, input[start:i])
+		default:
+			return nil, fmt.Errorf("unexpected character %q", ch)
+		}
+	}
+	return tokens, nil
+}
+
+// ruleParser is a recursive-descent parser over rule condition tokens
+type ruleParser struct {
+	tokens    []string
+	pos       int
+	variables map[string]bool
+}
+
+func (p *ruleParser) done() bool { return p.pos >= len(p.tokens) }
+
+func (p *ruleParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *ruleParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *ruleParser) parseOr() (ruleExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *ruleParser) parseAnd() (ruleExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "AND") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *ruleParser) parseUnary() (ruleExpr, error) {
+	switch token := p.peek(); {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of condition")
+	case strings.EqualFold(token, "NOT"):
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{inner}, nil
+	case token == "(":
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return inner, nil
+	default:
+		return p.parseComparison()
+	}
+}
+
+func (p *ruleParser) parseComparison() (ruleExpr, error) {
+	variable := p.next()
+	if !ruleVariables[variable] {
+		return nil, fmt.Errorf("unknown variable %q", variable)
+	}
+
+	op := p.next()
+	switch op {
+	case "<", "<=", ">", ">=", "==", "!=":
+	default:
+		return nil, fmt.Errorf("expected comparison operator after %s, got %q", variable, op)
+	}
+
+	valueText := p.next()
+	value, err := strconv.ParseFloat(valueText, 64)
+	if err != nil {
+		return nil, fmt.Errorf("expected number after %s %s, got %q", variable, op, valueText)
+	}
+
+	p.variables[variable] = true
+	return compareExpr{variable: variable, op: op, value: value}, nil
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"mindx/config"
+	"mindx/models"
+)
+
+// RulesScorer is a RiskScorer driven by rules loaded from a file.
+// The file can be reloaded while the service runs; evaluations always
+// use the last rule set that compiled successfully.
+type RulesScorer struct {
+	path   string
+	config *config.RiskConfig
+	window RateWindow
+	trend  TrendDetector
+
+	mu      sync.RWMutex
+	rules   *RuleSet
+	modTime time.Time
+}
+
+// NewRulesScorer creates a RulesScorer and loads cfg.RulesFile, returning an
+// error if the file is missing or any rule is invalid
+func NewRulesScorer(cfg *config.RiskConfig) (*RulesScorer, error) {
+	r := &RulesScorer{
+		path:   cfg.RulesFile,
+		config: cfg,
+		window: NewRateWindow(cfg),
+		trend:  NewTrendDetector(cfg),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// NewRulesScorerWithRules creates a RulesScorer that scores with rules instead of
+// a rules file, such as the rules sent to POST /evaluate/preview. It cannot be reloaded.
+func NewRulesScorerWithRules(cfg *config.RiskConfig, rules *RuleSet) *RulesScorer {
+	return &RulesScorer{
+		config: cfg,
+		window: NewRateWindow(cfg),
+		trend:  NewTrendDetector(cfg),
+		rules:  rules,
+	}
+}
+
+// Reload re-reads and compiles the rules file. On error the current rules are kept.
+func (r *RulesScorer) Reload() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+	rules, err := LoadRuleSet(r.path, r.config.MediumRiskThreshold, r.config.HighRiskThreshold)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.rules = rules
+	r.modTime = info.ModTime()
+	r.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the rules file whenever its modification time changes,
+// checking every interval until ctx is cancelled. Invalid edits are logged
+// and ignored so that a typo cannot take scoring down.
+func (r *RulesScorer) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(r.path)
+			if err != nil {
+				log.Printf("Failed to check rules file %s: %v", r.path, err)
+				continue
+			}
+
+			r.mu.RLock()
+			changed := !info.ModTime().Equal(r.modTime)
+			r.mu.RUnlock()
+			if !changed {
+				continue
+			}
+
+			if err := r.Reload(); err != nil {
+				log.Printf("Keeping previous risk rules, failed to reload %s: %v", r.path, err)
+				continue
+			}
+			log.Printf("Reloaded risk rules from %s", r.path)
+		}
+	}
+}
+
+// Rules returns the rule set currently in use
+func (r *RulesScorer) Rules() *RuleSet {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.rules
+}
+
+// Score implements RiskScorer
+func (r *RulesScorer) Score(input RiskInput) models.RiskEvaluation {
+	rules := r.Rules()
+
+	attendanceRate := r.window.AttendanceRate(input.Attendance, input.AsOf)
+	assignmentRate := r.window.AssignmentRate(input.Assignments, input.AsOf)
+	contactFailures := countContactFailures(input.Contacts)
+	trend := r.trend.Detect(input)
+
+	vars := map[string]float64{
+		"attendance_rate":    attendanceRate,
+		"assignment_rate":    assignmentRate,
+		"contact_failures":   float64(contactFailures),
+		"absences":           float64(countAbsences(input.Attendance)),
+		"missed_assignments": float64(countMissedAssignments(input.Assignments)),
+		"trend":              0,
+	}
+	if trend.Detected() {
+		vars["trend"] = 1
+	}
+
+	var riskFactors, details []string
+	var factors models.RiskFactors
+	score := 0
+	for _, rule := range rules.Rules {
+		if !rule.condition.eval(vars) {
+			continue
+		}
+		score += rule.Points
+		riskFactors = append(riskFactors, rule.Factor)
+		if rule.variables["trend"] && details == nil {
+			details = trend.Details
+		}
+		factors = append(factors, rule.factor(vars))
+	}
+	if score < 0 {
+		score = 0
+	}
+
+	var riskLevel models.RiskLevel
+	switch {
+	case score >= rules.High:
+		riskLevel = models.RiskLevelHigh
+	case score >= rules.Medium:
+		riskLevel = models.RiskLevelMedium
+	default:
+		riskLevel = models.RiskLevelLow
+	}
+
+	return models.RiskEvaluation{
+		Score:               score,
+		RiskLevel:           riskLevel,
+		Note:                riskNote(riskFactors, details),
+		Factors:             factors,
+		AttendanceRate:      attendanceRate,
+		AssignmentRate:      assignmentRate,
+		ContactFailures:     contactFailures,
+		ScoringMode:         config.ScoringModeRules,
+		AttendanceThreshold: r.config.AttendanceThreshold,
+		AssignmentThreshold: r.config.AssignmentThreshold,
+		ContactThreshold:    r.config.ContactThreshold,
+		MediumRiskThreshold: rules.Medium,
+		HighRiskThreshold:   rules.High,
+	}
+}
+
+// factor describes a matched rule. A rule made of a single comparison also
+// reports the measured value and the threshold it was compared against.
+func (r Rule) factor(vars map[string]float64) models.RiskFactor {
+	factor := models.RiskFactor{
+		Name:         r.Factor,
+		Weight:       float64(r.Points),
+		Contribution: float64(r.Points),
+		Triggered:    true,
+		Detail:       r.Source,
+	}
+	if cmp, ok := r.condition.(compareExpr); ok {
+		value, threshold := vars[cmp.variable], cmp.value
+		factor.Value = &value
+		factor.Threshold = &threshold
+		if strings.HasSuffix(cmp.variable, "_rate") {
+			factor.Unit = "%"
+		}
+	}
+	return factor
+}
+
+// countAbsences counts the number of absent attendance records
+func countAbsences(attendance []models.AttendanceRecord) int {
+	absences := 0
+	for _, a := range attendance {
+		if a.Status == models.AttendanceStatusAbsent {
+			absences++
+		}
+	}
+	return absences
+}
+
+// countMissedAssignments counts the number of assignments that were not submitted
+func countMissedAssignments(assignments []models.AssignmentRecord) int {
+	missed := 0
+	for _, a := range assignments {
+		if !a.Submitted {
+			missed++
+		}
+	}
+	return missed
+}
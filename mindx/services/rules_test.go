@@ -0,0 +1,245 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"mindx/config"
+	"mindx/models"
+	"mindx/repository"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		vars   map[string]float64
+		match  bool
+		points int
+		factor string
+		err    string
+	}{
+		{
+			name:   "comparison with a named factor",
+			source: `attendance_rate < 60 => +2 "low attendance"`,
+			vars:   map[string]float64{"attendance_rate": 59.5},
+			match:  true,
+			points: 2,
+			factor: "low attendance",
+		},
+		{
+			name:   "unnamed factor reports the condition",
+			source: `absences >= 3 => +1`,
+			vars:   map[string]float64{"absences": 2},
+			match:  false,
+			points: 1,
+			factor: "absences >= 3",
+		},
+		{
+			name:   "AND binds tighter than OR",
+			source: `trend == 1 OR contact_failures > 1 AND assignment_rate < 50 => +3 "disengaged"`,
+			vars:   map[string]float64{"contact_failures": 2, "assignment_rate": 80},
+			match:  false,
+			points: 3,
+			factor: "disengaged",
+		},
+		{
+			name:   "parentheses and NOT",
+			source: `NOT (missed_assignments != 0) and (attendance_rate >= 90 or absences <= 1) => -1 "keeping up"`,
+			vars:   map[string]float64{"attendance_rate": 85, "absences": 1},
+			match:  true,
+			points: -1,
+			factor: "keeping up",
+		},
+		{name: "missing arrow", source: `absences > 1 +1`, err: `expected exactly one "=>"`},
+		{name: "two arrows", source: `absences > 1 => +1 => +2`, err: `expected exactly one "=>"`},
+		{name: "unknown variable", source: `grade < 50 => +1`, err: `unknown variable "grade"`},
+		{name: "missing operator", source: `absences 3 => +1`, err: `expected comparison operator after absences, got "3"`},
+		{name: "invalid operator", source: `absences = 3 => +1`, err: `invalid operator at "= 3 "`},
+		{name: "missing number", source: `absences > many => +1`, err: `expected number after absences >, got "many"`},
+		{name: "missing closing parenthesis", source: `(absences > 1 => +1`, err: "missing closing parenthesis"},
+		{name: "trailing token", source: `absences > 1 absences => +1`, err: `unexpected "absences"`},
+		{name: "empty condition", source: ` => +1`, err: "unexpected end of condition"},
+		{name: "unsigned points", source: `absences > 1 => 2`, err: `action must start with +N or -N, got "2"`},
+		{name: "invalid points", source: `absences > 1 => +two`, err: `invalid points "+two"`},
+		{name: "unquoted factor", source: `absences > 1 => +1 absent`, err: "factor name must be a quoted string, got absent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRule(tt.source)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("ParseRule(%q) error = %v, want %q", tt.source, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRule(%q): %v", tt.source, err)
+			}
+			if rule.Points != tt.points || rule.Factor != tt.factor {
+				t.Errorf("rule = %+d %q, want %+d %q", rule.Points, rule.Factor, tt.points, tt.factor)
+			}
+			if got := rule.condition.eval(tt.vars); got != tt.match {
+				t.Errorf("condition with %v = %v, want %v", tt.vars, got, tt.match)
+			}
+		})
+	}
+}
+
+func TestCompileRuleFile(t *testing.T) {
+	tests := []struct {
+		name         string
+		file         RuleFile
+		medium, high int
+		err          string
+	}{
+		{
+			name:   "levels default to the configured thresholds",
+			file:   RuleFile{Rules: []string{`absences > 1 => +1`}},
+			medium: 2,
+			high:   3,
+		},
+		{
+			name:   "levels from the file",
+			file:   withLevels(RuleFile{Rules: []string{`absences > 1 => +1`}}, 1, 5),
+			medium: 1,
+			high:   5,
+		},
+		{name: "no rules", file: RuleFile{}, err: "rules file defines no rules"},
+		{
+			name: "high below medium",
+			file: withLevels(RuleFile{Rules: []string{`absences > 1 => +1`}}, 3, 1),
+			err:  "high level 1 is below medium level 3",
+		},
+		{
+			name: "invalid rule",
+			file: RuleFile{Rules: []string{`absences > 1 => +1`, `absences > => +1`}},
+			err:  `rule 2 "absences > => +1": expected number after absences >, got ""`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := CompileRuleFile(tt.file, 2, 3)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("CompileRuleFile error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if set.Medium != tt.medium || set.High != tt.high || len(set.Rules) != len(tt.file.Rules) {
+				t.Errorf("rule set has levels %d/%d and %d rules, want %d/%d and %d",
+					set.Medium, set.High, len(set.Rules), tt.medium, tt.high, len(tt.file.Rules))
+			}
+		})
+	}
+}
+
+// withLevels returns file with its medium and high levels set
+func withLevels(file RuleFile, medium, high int) RuleFile {
+	file.Levels.Medium, file.Levels.High = &medium, &high
+	return file
+}
+
+// testRuleSet compiles the rules used to score students in the tests below
+func testRuleSet(t *testing.T) *RuleSet {
+	t.Helper()
+	set, err := CompileRuleFile(withLevels(RuleFile{Rules: []string{
+		`attendance_rate < 60 => +2 "low attendance"`,
+		`contact_failures >= 2 AND NOT (assignment_rate >= 50) => +2 "unreachable and behind"`,
+		`absences > 0 => +1`,
+		`missed_assignments == 0 => -1 "keeping up"`,
+	}}, 2, 4), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return set
+}
+
+func TestRulesScorerStoresEvaluation(t *testing.T) {
+	disengaged := testStudent("S3", 5, 5)
+	disengaged.Assignments[0].Submitted = false
+	disengaged.Contacts = []models.ContactRecord{
+		{Date: "2025-06-07", Status: models.ContactStatusFailed},
+		{Date: "2025-06-14", Status: models.ContactStatusFailed},
+	}
+
+	tests := []struct {
+		name    string
+		student models.Student
+		score   int
+		level   models.RiskLevel
+		factors []string
+	}{
+		{
+			name:    "negative score is clamped to zero",
+			student: testStudent("S1", 10, 0),
+			score:   0,
+			level:   models.RiskLevelLow,
+			factors: []string{"keeping up"},
+		},
+		{
+			name:    "medium",
+			student: testStudent("S2", 5, 5),
+			score:   2,
+			level:   models.RiskLevelMedium,
+			factors: []string{"low attendance", "absences > 0", "keeping up"},
+		},
+		{
+			name:    "high",
+			student: disengaged,
+			score:   5,
+			level:   models.RiskLevelHigh,
+			factors: []string{"low attendance", "unreachable and behind", "absences > 0"},
+		},
+	}
+
+	forEachRepository(t, func(t *testing.T, repo repository.Repository) {
+		cfg := testConfig()
+		service := NewStudentServiceWithScorer(repo, NewRulesScorerWithRules(&cfg.Risk, testRuleSet(t)), cfg.Ingest)
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if _, err := service.CreateStudent(tt.student); err != nil {
+					t.Fatal(err)
+				}
+				stored, err := service.GetStudent(tt.student.StudentID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if *stored.DropoutScore != tt.score || *stored.DropoutRiskLevel != string(tt.level) {
+					t.Errorf("student scored %d %s, want %d %s",
+						*stored.DropoutScore, *stored.DropoutRiskLevel, tt.score, tt.level)
+				}
+
+				history, err := service.GetStudentEvaluations(tt.student.StudentID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(history) != 1 {
+					t.Fatalf("history has %d evaluations, want 1", len(history))
+				}
+				evaluation := history[0]
+				if evaluation.ScoringMode != config.ScoringModeRules || evaluation.MediumRiskThreshold != 2 || evaluation.HighRiskThreshold != 4 {
+					t.Errorf("evaluation recorded mode %s with levels %d/%d, want rules with 2/4",
+						evaluation.ScoringMode, evaluation.MediumRiskThreshold, evaluation.HighRiskThreshold)
+				}
+				var factors []string
+				for _, factor := range evaluation.Factors {
+					factors = append(factors, factor.Name)
+				}
+				if !reflect.DeepEqual(factors, tt.factors) {
+					t.Errorf("factors = %q, want %q", factors, tt.factors)
+				}
+				if !strings.Contains(evaluation.Note, tt.factors[0]) {
+					t.Errorf("note %q does not mention %q", evaluation.Note, tt.factors[0])
+				}
+			})
+		}
+	})
+}
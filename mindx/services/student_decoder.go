@@ -0,0 +1,310 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"mindx/models"
+)
+
+// FieldError describes one invalid field of an ingested student record
+type FieldError struct {
+	Index     int    `json:"index"`
+	StudentID string `json:"student_id,omitempty"`
+	Field     string `json:"field"`
+	Message   string `json:"message"`
+}
+
+// ValidationErrors lists every invalid field found in an ingested payload
+type ValidationErrors []FieldError
+
+// Error implements the error interface
+func (v ValidationErrors) Error() string {
+	if len(v) == 0 {
+		return "no validation errors"
+	}
+	first := v[0]
+	msg := fmt.Sprintf("record %d: %s: %s", first.Index, first.Field, first.Message)
+	if len(v) > 1 {
+		msg += fmt.Sprintf(" (and %d more)", len(v)-1)
+	}
+	return msg
+}
+
+// studentRecord is the typed ingestion shape of one student
+type studentRecord struct {
+	StudentID   string                    `json:"student_id"`
+	StudentName string                    `json:"student_name"`
+	Attendance  []models.AttendanceRecord `json:"attendance"`
+	Assignments []models.AssignmentRecord `json:"assignments"`
+	Contacts    []models.ContactRecord    `json:"contacts"`
+}
+
+// DecodeStudents decodes a JSON array of students into Student models.
+// Every record is decoded and validated on its own so that a single bad record
+// cannot hide the others; if any record is invalid the returned error is a
+// ValidationErrors listing all of them.
+func DecodeStudents(data []byte) ([]models.Student, error) {
+	students, errs, err := DecodeStudentsPartial(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return students, nil
+}
+
+// DecodeStudentsPartial decodes a JSON array of students, returning the valid
+// students alongside the validation errors of the records that were skipped.
+// The error is only set when data is not a JSON array.
+func DecodeStudentsPartial(data []byte) ([]models.Student, ValidationErrors, error) {
+	var rawRecords []json.RawMessage
+	if err := json.Unmarshal(data, &rawRecords); err != nil {
+		return nil, nil, err
+	}
+
+	var students []models.Student
+	var errs ValidationErrors
+	seen := make(map[string]int)
+
+	for i, raw := range rawRecords {
+		var record studentRecord
+		if err := unmarshalStrict(raw, &record); err != nil {
+			errs = append(errs, decodeFieldError(i, err))
+			continue
+		}
+
+		recordErrs := validateStudentRecord(i, &record)
+		if record.StudentID != "" {
+			if first, ok := seen[record.StudentID]; ok {
+				recordErrs = append(recordErrs, FieldError{
+					Index:     i,
+					StudentID: record.StudentID,
+					Field:     "student_id",
+					Message:   fmt.Sprintf("duplicate of record %d", first),
+				})
+			} else {
+				seen[record.StudentID] = i
+			}
+		}
+		if len(recordErrs) > 0 {
+			errs = append(errs, recordErrs...)
+			continue
+		}
+
+		students = append(students, record.toStudent())
+	}
+
+	return students, errs, nil
+}
+
+// DecodeStudent decodes and validates a single JSON student object.
+// Invalid fields are reported as ValidationErrors; the error is a
+// *json.SyntaxError when data is not valid JSON.
+func DecodeStudent(data []byte) (models.Student, error) {
+	return decodeStudentRecord(studentRecord{}, data)
+}
+
+// DecodeStudentUpdate applies a JSON student object to an existing student.
+// With replace set, every field not present in data is cleared as for a full
+// replacement; otherwise only the fields present in data are changed.
+// The student_id may be omitted but cannot be changed.
+func DecodeStudentUpdate(existing *models.Student, data []byte, replace bool) (models.Student, error) {
+	base := studentRecord{StudentID: existing.StudentID}
+	if !replace {
+		base = recordFromStudent(existing)
+	}
+
+	student, err := decodeStudentRecord(base, data)
+	if err != nil {
+		return student, err
+	}
+	if student.StudentID != existing.StudentID {
+		return student, ValidationErrors{{
+			StudentID: student.StudentID,
+			Field:     "student_id",
+			Message:   "cannot be changed",
+		}}
+	}
+	return student, nil
+}
+
+// decodeStudentRecord decodes data on top of record and validates the result
+func decodeStudentRecord(record studentRecord, data []byte) (models.Student, error) {
+	if err := unmarshalStrict(data, &record); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return models.Student{}, err
+		}
+		return models.Student{}, ValidationErrors{decodeFieldError(0, err)}
+	}
+	if errs := validateStudentRecord(0, &record); len(errs) > 0 {
+		return models.Student{}, ValidationErrors(errs)
+	}
+	return record.toStudent(), nil
+}
+
+// recordFromStudent converts a stored student back into its typed ingestion shape.
+// The records are copied so that appending to them leaves the student unchanged.
+func recordFromStudent(student *models.Student) studentRecord {
+	return studentRecord{
+		StudentID:   student.StudentID,
+		StudentName: student.StudentName,
+		Attendance:  append([]models.AttendanceRecord(nil), student.Attendance...),
+		Assignments: append([]models.AssignmentRecord(nil), student.Assignments...),
+		Contacts:    append([]models.ContactRecord(nil), student.Contacts...),
+	}
+}
+
+// Failures groups the validation errors by record into ingestion failures
+func (v ValidationErrors) Failures() []IngestionFailure {
+	failures := []IngestionFailure{}
+	byIndex := make(map[int]int)
+	for _, fieldErr := range v {
+		pos, ok := byIndex[fieldErr.Index]
+		if !ok {
+			index := fieldErr.Index
+			failures = append(failures, IngestionFailure{
+				Index:     &index,
+				StudentID: fieldErr.StudentID,
+				Reason:    "invalid student record",
+			})
+			pos = len(failures) - 1
+			byIndex[fieldErr.Index] = pos
+		}
+		failures[pos].Errors = append(failures[pos].Errors, fieldErr)
+	}
+	return failures
+}
+
+// unknownFieldError is the prefix of the error returned by a json.Decoder
+// disallowing unknown fields when it meets one, followed by the quoted field name
+const unknownFieldError = "json: unknown field "
+
+// unmarshalStrict decodes the JSON value data into v like json.Unmarshal, but
+// rejects object keys v has no field for, so that a misspelled field such as
+// "attendence" is reported instead of silently dropping its data
+func unmarshalStrict(data []byte, v interface{}) error {
+	if !json.Valid(data) {
+		// Report the syntax error as json.Unmarshal does
+		return json.Unmarshal(data, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// decodeFieldError converts a JSON decoding error of one record into a FieldError
+func decodeFieldError(index int, err error) FieldError {
+	if name, ok := strings.CutPrefix(err.Error(), unknownFieldError); ok {
+		if field, unquoteErr := strconv.Unquote(name); unquoteErr == nil {
+			name = field
+		}
+		return FieldError{Index: index, Field: name, Message: "unknown field"}
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		if typeErr.Field == "" {
+			return FieldError{Index: index, Message: "record must be a JSON object"}
+		}
+		return FieldError{
+			Index:   index,
+			Field:   typeErr.Field,
+			Message: fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value),
+		}
+	}
+	return FieldError{Index: index, Message: err.Error()}
+}
+
+// validateStudentRecord checks required fields, dates, status values and
+// duplicate entries within a single student record
+func validateStudentRecord(index int, record *studentRecord) []FieldError {
+	var errs []FieldError
+	fail := func(field, message string) {
+		errs = append(errs, FieldError{
+			Index:     index,
+			StudentID: record.StudentID,
+			Field:     field,
+			Message:   message,
+		})
+	}
+
+	if strings.TrimSpace(record.StudentID) == "" {
+		fail("student_id", "is required")
+	}
+	if strings.TrimSpace(record.StudentName) == "" {
+		fail("student_name", "is required")
+	}
+
+	dates := make(map[string]bool)
+	for i, a := range record.Attendance {
+		field := fmt.Sprintf("attendance[%d]", i)
+		if msg := validateDate(a.Date); msg != "" {
+			fail(field+".date", msg)
+		} else if dates[a.Date] {
+			fail(field+".date", "duplicate attendance date "+a.Date)
+		}
+		dates[a.Date] = true
+		if a.Status != models.AttendanceStatusAttend && a.Status != models.AttendanceStatusAbsent {
+			fail(field+".status", fmt.Sprintf("must be one of %s, %s", models.AttendanceStatusAttend, models.AttendanceStatusAbsent))
+		}
+	}
+
+	names := make(map[string]bool)
+	for i, a := range record.Assignments {
+		field := fmt.Sprintf("assignments[%d]", i)
+		if msg := validateDate(a.Date); msg != "" {
+			fail(field+".date", msg)
+		}
+		if strings.TrimSpace(a.Name) == "" {
+			fail(field+".name", "is required")
+		} else if names[a.Name] {
+			fail(field+".name", "duplicate assignment name "+a.Name)
+		}
+		names[a.Name] = true
+	}
+
+	dates = make(map[string]bool)
+	for i, ct := range record.Contacts {
+		field := fmt.Sprintf("contacts[%d]", i)
+		if msg := validateDate(ct.Date); msg != "" {
+			fail(field+".date", msg)
+		} else if dates[ct.Date] {
+			fail(field+".date", "duplicate contact date "+ct.Date)
+		}
+		dates[ct.Date] = true
+		if ct.Status != models.ContactStatusSuccess && ct.Status != models.ContactStatusFailed {
+			fail(field+".status", fmt.Sprintf("must be one of %s, %s", models.ContactStatusSuccess, models.ContactStatusFailed))
+		}
+	}
+
+	return errs
+}
+
+// validateDate returns a non-empty message if date is missing or not YYYY-MM-DD
+func validateDate(date string) string {
+	if date == "" {
+		return "is required"
+	}
+	if _, err := time.Parse(models.DateLayout, date); err != nil {
+		return "must be a date in YYYY-MM-DD format"
+	}
+	return ""
+}
+
+// toStudent converts a validated record into a Student model with its activity records
+func (r *studentRecord) toStudent() models.Student {
+	return models.Student{
+		StudentID:   r.StudentID,
+		StudentName: r.StudentName,
+		Attendance:  r.Attendance,
+		Assignments: r.Assignments,
+		Contacts:    r.Contacts,
+	}
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeStudentsPartial(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		students []string
+		errs     ValidationErrors
+	}{
+		{
+			name:     "valid records",
+			data:     `[{"student_id":"S1","student_name":"A","attendance":[{"date":"2025-06-01","status":"ATTEND"}]},{"student_id":"S2","student_name":"B"}]`,
+			students: []string{"S1", "S2"},
+		},
+		{
+			name:     "unknown field",
+			data:     `[{"student_id":"S1","student_name":"A","attendence":[]},{"student_id":"S2","student_name":"B"}]`,
+			students: []string{"S2"},
+			errs:     ValidationErrors{{Index: 0, Field: "attendence", Message: "unknown field"}},
+		},
+		{
+			name:     "unknown field of a record",
+			data:     `[{"student_id":"S1","student_name":"A","contacts":[{"date":"2025-06-01","status":"FAILED","channel":"phone"}]}]`,
+			students: nil,
+			errs:     ValidationErrors{{Index: 0, Field: "channel", Message: "unknown field"}},
+		},
+		{
+			name:     "missing required fields",
+			data:     `[{"student_id":"","student_name":" "}]`,
+			students: nil,
+			errs: ValidationErrors{
+				{Index: 0, Field: "student_id", Message: "is required"},
+				{Index: 0, Field: "student_name", Message: "is required"},
+			},
+		},
+		{
+			name:     "duplicate student",
+			data:     `[{"student_id":"S1","student_name":"A"},{"student_id":"S1","student_name":"B"}]`,
+			students: []string{"S1"},
+			errs:     ValidationErrors{{Index: 1, StudentID: "S1", Field: "student_id", Message: "duplicate of record 0"}},
+		},
+		{
+			name: "invalid records",
+			data: `[{"student_id":"S1","student_name":"A",
+				"attendance":[{"date":"2025-06-01","status":"LATE"},{"date":"2025-06-01","status":"ATTEND"}],
+				"assignments":[{"date":"06/01/2025","name":"HW 1"}]}]`,
+			students: nil,
+			errs: ValidationErrors{
+				{Index: 0, StudentID: "S1", Field: "attendance[0].status", Message: "must be one of ATTEND, ABSENT"},
+				{Index: 0, StudentID: "S1", Field: "attendance[1].date", Message: "duplicate attendance date 2025-06-01"},
+				{Index: 0, StudentID: "S1", Field: "assignments[0].date", Message: "must be a date in YYYY-MM-DD format"},
+			},
+		},
+		{
+			name:     "record not an object",
+			data:     `[1,{"student_id":"S2","student_name":"B"}]`,
+			students: []string{"S2"},
+			errs:     ValidationErrors{{Index: 0, Message: "record must be a JSON object"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			students, errs, err := DecodeStudentsPartial([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, student := range students {
+				ids = append(ids, student.StudentID)
+			}
+			if !reflect.DeepEqual(ids, tt.students) {
+				t.Errorf("students = %v, want %v", ids, tt.students)
+			}
+			if !reflect.DeepEqual(errs, tt.errs) {
+				t.Errorf("errors = %+v, want %+v", errs, tt.errs)
+			}
+		})
+	}
+}
+
+func TestDecodeStudentsPartialRejectsNonArray(t *testing.T) {
+	for _, data := range []string{`{"student_id":"S1"}`, `[{"student_id":`} {
+		if _, _, err := DecodeStudentsPartial([]byte(data)); err == nil {
+			t.Errorf("DecodeStudentsPartial(%s) returned no error", data)
+		}
+	}
+}
+
+func TestDecodeStudent(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		errs   ValidationErrors
+		syntax bool
+	}{
+		{name: "valid", data: `{"student_id":"S1","student_name":"A"}`},
+		{
+			name: "unknown field",
+			data: `{"student_id":"S1","student_name":"A","nickname":"B"}`,
+			errs: ValidationErrors{{Field: "nickname", Message: "unknown field"}},
+		},
+		{
+			name: "wrong type",
+			data: `{"student_id":"S1","student_name":5}`,
+			errs: ValidationErrors{{Field: "student_name", Message: "expected string, got number"}},
+		},
+		{name: "malformed", data: `{"student_id":`, syntax: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeStudent([]byte(tt.data))
+			var syntaxErr *json.SyntaxError
+			if tt.syntax {
+				if !errors.As(err, &syntaxErr) {
+					t.Fatalf("got %v, want a *json.SyntaxError", err)
+				}
+				return
+			}
+			var errs ValidationErrors
+			errors.As(err, &errs)
+			if (err == nil) != (tt.errs == nil) || !reflect.DeepEqual(errs, tt.errs) {
+				t.Errorf("got %v, want %+v", err, tt.errs)
+			}
+		})
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	errs := ValidationErrors{
+		{Index: 0, StudentID: "S1", Field: "student_name", Message: "is required"},
+		{Index: 2, Field: "attendence", Message: "unknown field"},
+		{Index: 0, StudentID: "S1", Field: "attendance[0].date", Message: "is required"},
+	}
+
+	if got, want := errs.Error(), "record 0: student_name: is required (and 2 more)"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	failures := errs.Failures()
+	if len(failures) != 2 {
+		t.Fatalf("Failures() returned %d failures, want 2", len(failures))
+	}
+	if *failures[0].Index != 0 || failures[0].StudentID != "S1" || len(failures[0].Errors) != 2 {
+		t.Errorf("first failure = %+v, want record 0 of S1 with 2 errors", failures[0])
+	}
+	if *failures[1].Index != 2 || len(failures[1].Errors) != 1 {
+		t.Errorf("second failure = %+v, want record 2 with 1 error", failures[1])
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"mindx/models"
+)
+
+// studentField is a field of the students list that can be requested with fields=
+type studentField struct {
+	value func(*models.Student) interface{}
+}
+
+// studentFields is the whitelist of selectable fields, keyed by JSON name
+var studentFields = map[string]studentField{
+	"id":                 {value: func(s *models.Student) interface{} { return s.ID }},
+	"student_id":         {value: func(s *models.Student) interface{} { return s.StudentID }},
+	"student_name":       {value: func(s *models.Student) interface{} { return s.StudentName }},
+	"attendance":         {value: func(s *models.Student) interface{} { return s.Attendance }},
+	"assignments":        {value: func(s *models.Student) interface{} { return s.Assignments }},
+	"contacts":           {value: func(s *models.Student) interface{} { return s.Contacts }},
+	"dropout_score":      {value: func(s *models.Student) interface{} { return s.DropoutScore }},
+	"dropout_risk_level": {value: func(s *models.Student) interface{} { return s.DropoutRiskLevel }},
+	"dropout_note":       {value: func(s *models.Student) interface{} { return s.DropoutNote }},
+	"dropout_factors":    {value: func(s *models.Student) interface{} { return s.DropoutFactors }},
+	"attendance_rate":    {value: func(s *models.Student) interface{} { return s.AttendanceRate }},
+	"assignment_rate":    {value: func(s *models.Student) interface{} { return s.AssignmentRate }},
+	"contact_failures":   {value: func(s *models.Student) interface{} { return s.ContactFailures }},
+	"created_at":         {value: func(s *models.Student) interface{} { return s.CreatedAt }},
+	"updated_at":         {value: func(s *models.Student) interface{} { return s.UpdatedAt }},
+}
+
+// DefaultStudentFields is the compact summary returned when no fields are requested.
+// It leaves out the attendance, assignments and contacts arrays in favour of the
+// rates computed from them.
+var DefaultStudentFields = []string{
+	"id",
+	"student_id",
+	"student_name",
+	"dropout_score",
+	"dropout_risk_level",
+	"dropout_note",
+	"dropout_factors",
+	"attendance_rate",
+	"assignment_rate",
+	"contact_failures",
+	"created_at",
+	"updated_at",
+}
+
+// StudentView is a student rendered with a subset of its fields, in the requested order
+type StudentView struct {
+	fields  []string
+	student *models.Student
+}
+
+// MarshalJSON implements json.Marshaler
+func (v StudentView) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range v.fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(studentFields[name].value(v.student))
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// parseStudentFields validates requested field names, defaulting to DefaultStudentFields
+func parseStudentFields(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return DefaultStudentFields, nil
+	}
+
+	var fields []string
+	seen := make(map[string]bool)
+	for _, name := range requested {
+		name = strings.TrimSpace(name)
+		if _, ok := studentFields[name]; !ok {
+			return nil, &QueryError{Param: "fields", Message: "unknown field " + name}
+		}
+		if !seen[name] {
+			seen[name] = true
+			fields = append(fields, name)
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"mindx/models"
+)
+
+// hashedInput is the part of a student covered by its input hash
+type hashedInput struct {
+	StudentName string                    `json:"student_name"`
+	Attendance  []models.AttendanceRecord `json:"attendance"`
+	Assignments []models.AssignmentRecord `json:"assignments"`
+	Contacts    []models.ContactRecord    `json:"contacts"`
+}
+
+// inputHash returns the hex SHA-256 hash of the name and activity records of student.
+// Records are sorted first, so the hash does not depend on the order they were sent in.
+func inputHash(student *models.Student) string {
+	input := hashedInput{
+		StudentName: student.StudentName,
+		Attendance:  append([]models.AttendanceRecord{}, student.Attendance...),
+		Assignments: append([]models.AssignmentRecord{}, student.Assignments...),
+		Contacts:    append([]models.ContactRecord{}, student.Contacts...),
+	}
+	sort.Slice(input.Attendance, func(i, j int) bool { return input.Attendance[i].Date < input.Attendance[j].Date })
+	sort.Slice(input.Assignments, func(i, j int) bool { return input.Assignments[i].Name < input.Assignments[j].Name })
+	sort.Slice(input.Contacts, func(i, j int) bool { return input.Contacts[i].Date < input.Contacts[j].Date })
+
+	// Records marshal without their IDs, so only the data sent is hashed
+	data, _ := json.Marshal(input)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
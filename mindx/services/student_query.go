@@ -0,0 +1,215 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"mindx/models"
+	"mindx/repository"
+)
+
+// Pagination limits for the students list
+const (
+	DefaultStudentPageSize = 100
+	MaxStudentPageSize     = 1000
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+// or was issued for a different sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// StudentQuery describes filtering, sorting and pagination of the students list.
+// Cursor and Offset are alternative ways to select a page; Cursor takes the
+// next_cursor of a previous page and is stable under concurrent inserts.
+type StudentQuery struct {
+	// RiskLevels keeps students at any of these levels
+	RiskLevels []string
+	// MinScore and MaxScore bound the dropout score, inclusive
+	MinScore *int
+	MaxScore *int
+	// Search matches a case-insensitive substring of the student ID or name
+	Search string
+	// UpdatedSince keeps students updated at or after this Unix time
+	UpdatedSince *int64
+	// Factors keeps students flagged for every one of these risk factors
+	Factors []string
+	// Fields selects the fields of each student; empty means DefaultStudentFields
+	Fields []string
+
+	SortBy string
+	Limit  int
+	Offset int
+	Cursor string
+}
+
+// QueryError reports an invalid students list parameter
+type QueryError struct {
+	Param   string
+	Message string
+}
+
+// Error implements the error interface
+func (e *QueryError) Error() string {
+	return "invalid " + e.Param + ": " + e.Message
+}
+
+// StudentPage is one page of the students list
+type StudentPage struct {
+	Students   []StudentView `json:"students"`
+	Total      int64         `json:"total"`
+	Limit      int           `json:"limit"`
+	Offset     int           `json:"offset"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// studentCursor is the decoded form of a keyset pagination cursor
+type studentCursor struct {
+	Sort   string            `json:"sort"`
+	Values []json.RawMessage `json:"values"`
+}
+
+// sortField is a field accepted by the sort_by parameter. Fields sort in
+// their default direction unless suffixed with :asc or :desc.
+type sortField struct {
+	name        string
+	defaultDesc bool
+}
+
+// studentSortFields is the whitelist of sortable fields; score and risk_level
+// sort highest first by default, with unevaluated students last in both directions
+var studentSortFields = map[string]sortField{
+	"student_id":   {name: repository.SortStudentID},
+	"student_name": {name: repository.SortStudentName},
+	"created_at":   {name: repository.SortCreatedAt},
+	"updated_at":   {name: repository.SortUpdatedAt},
+	"score":        {name: repository.SortScore, defaultDesc: true},
+	"risk_level":   {name: repository.SortRiskLevel, defaultDesc: true},
+}
+
+// parseStudentSort parses a sort_by value such as "risk_level,score:asc,student_name"
+// into sort keys ending with the student_id tie-breaker. The legacy values
+// risk_level_asc and score_asc are accepted as aliases for risk_level:asc and
+// score:asc. It also returns the normalised sort, which identifies the order in cursors.
+func parseStudentSort(sortBy string) ([]repository.SortKey, string, error) {
+	var keys []repository.SortKey
+	var normalized []string
+	hasStudentID := false
+
+	for _, token := range strings.Split(sortBy, ",") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+
+		name, direction, hasDirection := strings.Cut(token, ":")
+		if _, ok := studentSortFields[name]; !ok && !hasDirection && strings.HasSuffix(name, "_asc") {
+			name, direction, hasDirection = strings.TrimSuffix(name, "_asc"), "asc", true
+		}
+		field, ok := studentSortFields[name]
+		if !ok {
+			return nil, "", &QueryError{Param: "sort_by", Message: "unknown sort field " + name}
+		}
+
+		desc := field.defaultDesc
+		if hasDirection {
+			switch direction {
+			case "asc":
+				desc = false
+			case "desc":
+				desc = true
+			default:
+				return nil, "", &QueryError{Param: "sort_by", Message: "sort direction must be asc or desc, got " + direction}
+			}
+		}
+
+		keys = append(keys, repository.SortKey{Field: field.name, Desc: desc})
+		if desc {
+			normalized = append(normalized, name+":desc")
+		} else {
+			normalized = append(normalized, name+":asc")
+		}
+		if name == "student_id" {
+			hasStudentID = true
+		}
+	}
+
+	if !hasStudentID {
+		keys = append(keys, repository.SortKey{Field: repository.SortStudentID})
+		normalized = append(normalized, "student_id:asc")
+	}
+	return keys, strings.Join(normalized, ","), nil
+}
+
+// studentFilter validates the filters of q and converts them for the repository
+func studentFilter(q StudentQuery) (repository.StudentFilter, error) {
+	for _, level := range q.RiskLevels {
+		switch models.RiskLevel(level) {
+		case models.RiskLevelLow, models.RiskLevelMedium, models.RiskLevelHigh:
+		default:
+			return repository.StudentFilter{}, &QueryError{Param: "risk_level", Message: "must be LOW, MEDIUM or HIGH, got " + level}
+		}
+	}
+	if q.MinScore != nil && q.MaxScore != nil && *q.MinScore > *q.MaxScore {
+		return repository.StudentFilter{}, &QueryError{Param: "min_score", Message: "must not exceed max_score"}
+	}
+
+	return repository.StudentFilter{
+		RiskLevels:   q.RiskLevels,
+		MinScore:     q.MinScore,
+		MaxScore:     q.MaxScore,
+		Search:       q.Search,
+		UpdatedSince: q.UpdatedSince,
+		Factors:      q.Factors,
+	}, nil
+}
+
+// encodeCursor builds the cursor that resumes the list after student
+func encodeCursor(sortBy string, keys []repository.SortKey, student *models.Student) (string, error) {
+	cursor := studentCursor{Sort: sortBy}
+	for _, key := range keys {
+		raw, err := json.Marshal(key.Value(student))
+		if err != nil {
+			return "", err
+		}
+		cursor.Values = append(cursor.Values, raw)
+	}
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor returns the sort key values of the encoded cursor position
+func decodeCursor(sortBy string, keys []repository.SortKey, encoded string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor studentCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Sort != sortBy || len(cursor.Values) != len(keys) {
+		return nil, ErrInvalidCursor
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		if key.Numeric() {
+			var v int64
+			err = json.Unmarshal(cursor.Values[i], &v)
+			values[i] = v
+		} else {
+			var v string
+			err = json.Unmarshal(cursor.Values[i], &v)
+			values[i] = v
+		}
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+
+	"mindx/models"
+	"mindx/repository"
+)
+
+func TestStudentCursor(t *testing.T) {
+	score, level := 2, string(models.RiskLevelMedium)
+	evaluated := &models.Student{StudentID: "S1", StudentName: "Ann", DropoutScore: &score, DropoutRiskLevel: &level, CreatedAt: 1750000000}
+	unevaluated := &models.Student{StudentID: "S2", StudentName: "Bob"}
+
+	for _, sortBy := range []string{"", "student_name", "created_at:desc", "score", "score:asc", "risk_level,score:asc", "risk_level:asc"} {
+		keys, normalized, err := parseStudentSort(sortBy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, student := range []*models.Student{evaluated, unevaluated} {
+			cursor, err := encodeCursor(normalized, keys, student)
+			if err != nil {
+				t.Fatal(err)
+			}
+			values, err := decodeCursor(normalized, keys, cursor)
+			if err != nil {
+				t.Fatalf("decoding the %q cursor of %s: %v", sortBy, student.StudentID, err)
+			}
+			for i, key := range keys {
+				if values[i] != key.Value(student) {
+					t.Errorf("%q cursor of %s has %s = %v, want %v", sortBy, student.StudentID, key.Field, values[i], key.Value(student))
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeCursorRejectsInvalidCursors(t *testing.T) {
+	encode := func(data string) string { return base64.RawURLEncoding.EncodeToString([]byte(data)) }
+
+	tests := []struct {
+		name   string
+		sortBy string
+		cursor string
+	}{
+		{name: "not base64", sortBy: "", cursor: "not a cursor!"},
+		{name: "not JSON", sortBy: "", cursor: encode("student_id:asc")},
+		{name: "another sort", sortBy: "score", cursor: encode(`{"sort":"student_id:asc","values":["S1"]}`)},
+		{name: "too few values", sortBy: "score", cursor: encode(`{"sort":"score:desc,student_id:asc","values":[2]}`)},
+		{name: "too many values", sortBy: "", cursor: encode(`{"sort":"student_id:asc","values":["S1","S2"]}`)},
+		{name: "string for a number", sortBy: "score", cursor: encode(`{"sort":"score:desc,student_id:asc","values":["2","S1"]}`)},
+		{name: "number for a string", sortBy: "", cursor: encode(`{"sort":"student_id:asc","values":[1]}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, normalized, err := parseStudentSort(tt.sortBy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := decodeCursor(normalized, keys, tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("decodeCursor: got %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+// scoredStudent returns a student with the given count mode score from 0 to 3
+func scoredStudent(studentID string, score int) models.Student {
+	student := testStudent(studentID, 10, 0)
+	if score >= 1 {
+		student = testStudent(studentID, 2, 8)
+	}
+	if score >= 2 {
+		student.Assignments[0].Submitted = false
+	}
+	if score >= 3 {
+		student.Contacts = []models.ContactRecord{
+			{Date: "2025-06-07", Status: models.ContactStatusFailed},
+			{Date: "2025-06-14", Status: models.ContactStatusFailed},
+		}
+	}
+	return student
+}
+
+func TestGetStudentsWithFiltersPagesWithTies(t *testing.T) {
+	// Scores 0 and 1 are LOW, 2 MEDIUM and 3 HIGH. A1 and A2 are never evaluated
+	// and come last in every score and risk level order, despite their IDs.
+	scores := map[string]int{"S1": 2, "S2": 0, "S3": 3, "S4": 1, "S5": 2, "S6": 0, "S7": 3, "S8": 2}
+
+	tests := []struct {
+		sortBy string
+		want   []string
+	}{
+		{"", []string{"A1", "A2", "S1", "S2", "S3", "S4", "S5", "S6", "S7", "S8"}},
+		{"score", []string{"S3", "S7", "S1", "S5", "S8", "S4", "S2", "S6", "A1", "A2"}},
+		{"score:asc", []string{"S2", "S6", "S4", "S1", "S5", "S8", "S3", "S7", "A1", "A2"}},
+		{"risk_level", []string{"S3", "S7", "S1", "S5", "S8", "S2", "S4", "S6", "A1", "A2"}},
+		{"risk_level_asc", []string{"S2", "S4", "S6", "S1", "S5", "S8", "S3", "S7", "A1", "A2"}},
+		{"risk_level,score:asc", []string{"S3", "S7", "S1", "S5", "S8", "S2", "S6", "S4", "A1", "A2"}},
+	}
+
+	forEachRepository(t, func(t *testing.T, repo repository.Repository) {
+		service := NewStudentService(repo, testConfig())
+
+		var students []models.Student
+		for id, score := range scores {
+			students = append(students, scoredStudent(id, score))
+		}
+		if _, err := service.ProcessAndEvaluateStudents(students); err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []string{"A1", "A2"} {
+			if err := repo.CreateStudent(&models.Student{StudentID: id, StudentName: "Student " + id}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.sortBy, func(t *testing.T) {
+				var got []string
+				q := StudentQuery{SortBy: tt.sortBy, Limit: 3}
+				for pages := 0; pages <= len(tt.want); pages++ {
+					page, err := service.GetStudentsWithFilters(q)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if page.Total != int64(len(tt.want)) {
+						t.Errorf("page total = %d, want %d", page.Total, len(tt.want))
+					}
+					for _, view := range page.Students {
+						got = append(got, view.student.StudentID)
+					}
+					if page.NextCursor == "" {
+						break
+					}
+					q.Cursor = page.NextCursor
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("pages list %v, want %v", got, tt.want)
+				}
+			})
+		}
+
+		// A cursor only resumes the order it was issued for
+		page, err := service.GetStudentsWithFilters(StudentQuery{SortBy: "score", Limit: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := service.GetStudentsWithFilters(StudentQuery{SortBy: "score:asc", Limit: 3, Cursor: page.NextCursor}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("resuming score with a score:asc cursor: got %v, want ErrInvalidCursor", err)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"mindx/models"
+)
+
+// Record types that can be appended to a student
+const (
+	RecordTypeAttendance  = "attendance"
+	RecordTypeAssignments = "assignments"
+	RecordTypeContacts    = "contacts"
+)
+
+// AppendResult reports the outcome of appending records to a student
+type AppendResult struct {
+	// Added is the number of records stored
+	Added int `json:"added"`
+	// Duplicates is the number of records skipped because the student already had
+	// an attendance or contact record on the same date, or an assignment with the same name
+	Duplicates int             `json:"duplicates"`
+	Student    *models.Student `json:"student"`
+}
+
+// appendStudentRecords decodes one record or a JSON array of records of recordType
+// from data and picks those the student does not have yet.
+// It returns the student with the new records appended, a student holding only
+// the new records, and the number of duplicates skipped.
+func appendStudentRecords(existing *models.Student, recordType string, data []byte) (models.Student, models.Student, int, error) {
+	record := recordFromStudent(existing)
+
+	// Decode and validate the incoming records on their own, so that field
+	// errors point at the request rather than the stored records
+	incoming := studentRecord{
+		StudentID:   existing.StudentID,
+		StudentName: existing.StudentName,
+	}
+	var target interface{}
+	switch recordType {
+	case RecordTypeAttendance:
+		target = &incoming.Attendance
+	case RecordTypeAssignments:
+		target = &incoming.Assignments
+	case RecordTypeContacts:
+		target = &incoming.Contacts
+	default:
+		return models.Student{}, models.Student{}, 0, fmt.Errorf("unknown record type %q", recordType)
+	}
+	if err := decodeRecordList(data, target); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return models.Student{}, models.Student{}, 0, err
+		}
+		fieldErr := decodeFieldError(0, err)
+		fieldErr.StudentID = existing.StudentID
+		if fieldErr.Field != "" {
+			fieldErr.Field = recordType + "." + fieldErr.Field
+		}
+		return models.Student{}, models.Student{}, 0, ValidationErrors{fieldErr}
+	}
+	if errs := validateStudentRecord(0, &incoming); len(errs) > 0 {
+		return models.Student{}, models.Student{}, 0, ValidationErrors(errs)
+	}
+
+	added := studentRecord{
+		StudentID:   existing.StudentID,
+		StudentName: existing.StudentName,
+	}
+	switch recordType {
+	case RecordTypeAttendance:
+		seen := make(map[string]bool)
+		for _, a := range record.Attendance {
+			seen[a.Date] = true
+		}
+		for _, a := range incoming.Attendance {
+			if !seen[a.Date] {
+				added.Attendance = append(added.Attendance, a)
+			}
+		}
+		record.Attendance = append(record.Attendance, added.Attendance...)
+	case RecordTypeAssignments:
+		seen := make(map[string]bool)
+		for _, a := range record.Assignments {
+			seen[a.Name] = true
+		}
+		for _, a := range incoming.Assignments {
+			if !seen[a.Name] {
+				added.Assignments = append(added.Assignments, a)
+			}
+		}
+		record.Assignments = append(record.Assignments, added.Assignments...)
+	case RecordTypeContacts:
+		seen := make(map[string]bool)
+		for _, ct := range record.Contacts {
+			seen[ct.Date] = true
+		}
+		for _, ct := range incoming.Contacts {
+			if !seen[ct.Date] {
+				added.Contacts = append(added.Contacts, ct)
+			}
+		}
+		record.Contacts = append(record.Contacts, added.Contacts...)
+	}
+
+	duplicates := recordCount(&incoming) - recordCount(&added)
+	return record.toStudent(), added.toStudent(), duplicates, nil
+}
+
+// recordCount returns the number of activity records held by record
+func recordCount(record *studentRecord) int {
+	return len(record.Attendance) + len(record.Assignments) + len(record.Contacts)
+}
+
+// decodeRecordList decodes either a single JSON object or an array of objects into the slice target points to
+func decodeRecordList(data []byte, target interface{}) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		data = append(append([]byte{'['}, data...), ']')
+	}
+	return unmarshalStrict(data, target)
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"mindx/repository"
+)
+
+func TestAppendStudentRecords(t *testing.T) {
+	// testStudent("S1", 3, 0) attended 2025-06-01 to 2025-06-03, submitted
+	// "HW 1" and was contacted on 2025-06-07
+	tests := []struct {
+		name       string
+		recordType string
+		data       string
+		added      int
+		duplicates int
+		counts     [3]int
+		errs       ValidationErrors
+	}{
+		{
+			name:       "attendance on new and known dates",
+			recordType: RecordTypeAttendance,
+			data:       `[{"date":"2025-06-03","status":"ABSENT"},{"date":"2025-06-04","status":"ABSENT"},{"date":"2025-06-05","status":"ATTEND"}]`,
+			added:      2,
+			duplicates: 1,
+			counts:     [3]int{5, 1, 1},
+		},
+		{
+			name:       "single assignment object",
+			recordType: RecordTypeAssignments,
+			data:       `{"date":"2025-06-14","name":"HW 2","submitted":false}`,
+			added:      1,
+			counts:     [3]int{3, 2, 1},
+		},
+		{
+			name:       "assignment with a known name",
+			recordType: RecordTypeAssignments,
+			data:       `[{"date":"2025-06-14","name":"HW 1","submitted":false}]`,
+			duplicates: 1,
+			counts:     [3]int{3, 1, 1},
+		},
+		{
+			name:       "contacts",
+			recordType: RecordTypeContacts,
+			data:       `[{"date":"2025-06-07","status":"FAILED"},{"date":"2025-06-14","status":"FAILED"}]`,
+			added:      1,
+			duplicates: 1,
+			counts:     [3]int{3, 1, 2},
+		},
+		{
+			name:       "invalid record",
+			recordType: RecordTypeAttendance,
+			data:       `[{"date":"2025-06-04","status":"ABSENT"},{"date":"2025-06-05","status":"LATE"}]`,
+			counts:     [3]int{3, 1, 1},
+			errs:       ValidationErrors{{Index: 0, StudentID: "S1", Field: "attendance[1].status", Message: "must be one of ATTEND, ABSENT"}},
+		},
+		{
+			name:       "unknown field",
+			recordType: RecordTypeContacts,
+			data:       `{"date":"2025-06-14","status":"FAILED","channel":"phone"}`,
+			counts:     [3]int{3, 1, 1},
+			errs:       ValidationErrors{{Index: 0, StudentID: "S1", Field: "contacts.channel", Message: "unknown field"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forEachRepository(t, func(t *testing.T, repo repository.Repository) {
+				service := NewStudentService(repo, testConfig())
+				if _, err := service.CreateStudent(testStudent("S1", 3, 0)); err != nil {
+					t.Fatal(err)
+				}
+
+				result, err := service.AppendStudentRecords("S1", tt.recordType, []byte(tt.data))
+				if tt.errs != nil {
+					var errs ValidationErrors
+					if !errors.As(err, &errs) || !reflect.DeepEqual(errs, tt.errs) {
+						t.Fatalf("AppendStudentRecords error = %v, want %v", err, tt.errs)
+					}
+				} else if err != nil {
+					t.Fatal(err)
+				} else if result.Added != tt.added || result.Duplicates != tt.duplicates {
+					t.Errorf("appended %d with %d duplicates, want %d with %d",
+						result.Added, result.Duplicates, tt.added, tt.duplicates)
+				}
+
+				stored, err := service.GetStudent("S1")
+				if err != nil {
+					t.Fatal(err)
+				}
+				counts := [3]int{len(stored.Attendance), len(stored.Assignments), len(stored.Contacts)}
+				if counts != tt.counts {
+					t.Errorf("student has %v attendance, assignment and contact records, want %v", counts, tt.counts)
+				}
+
+				// Only appends that store records are evaluated
+				history, err := service.GetStudentEvaluations("S1")
+				if err != nil {
+					t.Fatal(err)
+				}
+				evaluations := 1
+				if tt.added > 0 {
+					evaluations++
+				}
+				if len(history) != evaluations {
+					t.Errorf("history has %d evaluations, want %d", len(history), evaluations)
+				}
+				if tt.errs != nil {
+					return
+				}
+
+				// Appending the same records again only finds duplicates
+				again, err := service.AppendStudentRecords("S1", tt.recordType, []byte(tt.data))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if again.Added != 0 || again.Duplicates != tt.added+tt.duplicates {
+					t.Errorf("appending again added %d with %d duplicates, want 0 with %d",
+						again.Added, again.Duplicates, tt.added+tt.duplicates)
+				}
+			})
+		})
+	}
+}
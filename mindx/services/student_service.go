@@ -1,259 +1,569 @@
 package services
 
 import (
+	"errors"
 	"fmt"
-	"strings"
+	"reflect"
+	"runtime"
+	"sync"
 
 	"mindx/config"
 	"mindx/models"
-
-	"gorm.io/gorm"
+	"mindx/repository"
 )
 
 // StudentService handles business logic for student data
 type StudentService struct {
-	db     *gorm.DB
-	config *config.RiskConfig
+	repo   repository.Repository
+	scorer RiskScorer
+	// batchSize is the number of students written by one bulk upsert
+	batchSize int
+	// workers is the number of goroutines scoring students in parallel
+	workers int
+}
+
+// ErrStudentExists is returned when creating a student whose student_id is already taken
+var ErrStudentExists = errors.New("student already exists")
+
+// Outcomes of storing one student during batch ingestion
+const (
+	OutcomeCreated = "created"
+	OutcomeUpdated = "updated"
+	// OutcomeUnchanged is a student whose name, records and evaluation are the
+	// ones already stored, and that was therefore not written
+	OutcomeUnchanged = "unchanged"
+)
+
+// IngestionReport lists the outcome of every student in a partial ingestion run
+type IngestionReport struct {
+	Created   []models.Student   `json:"created"`
+	Updated   []models.Student   `json:"updated"`
+	Unchanged []models.Student   `json:"unchanged"`
+	Failed    []IngestionFailure `json:"failed"`
+}
+
+// IngestionFailure describes a student that was rejected during partial ingestion.
+// Index is set when the record was rejected by validation before reaching the database.
+type IngestionFailure struct {
+	Index     *int         `json:"index,omitempty"`
+	StudentID string       `json:"student_id,omitempty"`
+	Reason    string       `json:"reason"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// NewStudentService creates a new StudentService instance using the rule-based scorer
+func NewStudentService(repo repository.Repository, cfg *config.Config) *StudentService {
+	return NewStudentServiceWithScorer(repo, NewRuleBasedScorer(&cfg.Risk), cfg.Ingest)
 }
 
-// NewStudentService creates a new StudentService instance
-func NewStudentService(db *gorm.DB) *StudentService {
+// NewStudentServiceWithScorer creates a new StudentService instance that evaluates with scorer.
+// Batch ingestion writes ingest.BatchSize students per upsert and scores them with
+// ingest.Concurrency goroutines, one per CPU by default.
+func NewStudentServiceWithScorer(repo repository.Repository, scorer RiskScorer, ingest config.IngestConfig) *StudentService {
+	batchSize := ingest.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	workers := ingest.Concurrency
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
 	return &StudentService{
-		db:     db,
-		config: &config.LoadConfig().Risk,
+		repo:      repo,
+		scorer:    scorer,
+		batchSize: batchSize,
+		workers:   workers,
 	}
 }
 
 // ProcessAndEvaluateStudents processes student data from JSON file, evaluates risk, and stores results
 func (s *StudentService) ProcessAndEvaluateStudents(students []models.Student) ([]models.Student, error) {
-	// Begin transaction
-	tx := s.db.Begin()
-	if tx.Error != nil {
-		return nil, tx.Error
+	var updatedStudents []models.Student
+
+	err := s.processStudents(students, func(student models.Student, outcome string) {
+		updatedStudents = append(updatedStudents, student)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	var updatedStudents []models.Student
+	return updatedStudents, nil
+}
 
-	// Process each student
-	for i := range students {
-		// Check if student already exists
-		var existingStudent models.Student
-		result := tx.Where("student_id = ?", students[i].StudentID).First(&existingStudent)
-		
-		var student models.Student
-		if result.Error == nil {
-			// Student exists, update record
-			if err := tx.Model(&existingStudent).Updates(map[string]interface{}{
-				"student_name": students[i].StudentName,
-				"attendance":   students[i].Attendance,
-				"assignments":  students[i].Assignments,
-				"contacts":     students[i].Contacts,
-			}).Error; err != nil {
-				tx.Rollback()
-				return nil, err
+// processStudents processes every student in one transaction, passing each stored
+// student to onStudent along with its outcome. Nothing is stored if any student fails.
+// Students are scored in parallel and written in bulk.
+func (s *StudentService) processStudents(students []models.Student, onStudent func(student models.Student, outcome string)) error {
+	evaluations := s.scoreStudents(students)
+	return s.repo.Transaction(func(tx repository.Repository) error {
+		for _, batch := range s.batches(students) {
+			if err := s.upsertBatch(tx, students[batch[0]:batch[1]], evaluations[batch[0]:batch[1]], onStudent); err != nil {
+				return err
 			}
-			student = existingStudent
-		} else if result.Error == gorm.ErrRecordNotFound {
-			// Student doesn't exist, create new record
-			if err := tx.Create(&students[i]).Error; err != nil {
-				tx.Rollback()
-				return nil, err
+		}
+		return nil
+	})
+}
+
+// scoreStudents evaluates the risk of every student from the records it holds and
+// sets its input hash, spreading the students over s.workers goroutines. Scoring
+// needs no database access, so it runs before any write.
+func (s *StudentService) scoreStudents(students []models.Student) []models.RiskEvaluation {
+	evaluations := make([]models.RiskEvaluation, len(students))
+	workers := s.workers
+	if workers > len(students) {
+		workers = len(students)
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				evaluations[i] = EvaluateStudent(s.scorer, &students[i])
+				hash := inputHash(&students[i])
+				students[i].InputHash = &hash
 			}
-			student = students[i]
-		} else {
-			// Other error
-			tx.Rollback()
-			return nil, result.Error
+		}()
+	}
+	for i := range students {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return evaluations
+}
+
+// batches splits students into [start, end) ranges of at most s.batchSize students.
+// A new batch also starts when a student_id repeats, as one upsert cannot write
+// the same student twice.
+func (s *StudentService) batches(students []models.Student) [][2]int {
+	var batches [][2]int
+	start := 0
+	seen := make(map[string]bool)
+	for i := range students {
+		if i-start == s.batchSize || seen[students[i].StudentID] {
+			batches = append(batches, [2]int{start, i})
+			start = i
+			seen = make(map[string]bool)
 		}
+		seen[students[i].StudentID] = true
+	}
+	if start < len(students) {
+		batches = append(batches, [2]int{start, len(students)})
+	}
+	return batches
+}
+
+// upsertBatch stores a batch of scored students with one bulk upsert within tx,
+// skipping unchanged students, then reloads them and passes each to onStudent
+func (s *StudentService) upsertBatch(tx repository.Repository, students []models.Student, evaluations []models.RiskEvaluation, onStudent func(student models.Student, outcome string)) error {
+	studentIDs := make([]string, len(students))
+	for i := range students {
+		studentIDs[i] = students[i].StudentID
+	}
+	existing, err := tx.FindStudents(studentIDs, repository.FindOptions{IncludeDeleted: true})
+	if err != nil {
+		return err
+	}
+	current := make(map[string]*models.Student, len(existing))
+	for i := range existing {
+		current[existing[i].StudentID] = &existing[i]
+	}
 
-		// Evaluate risk
-		score, riskLevel, note, err := s.evaluateRisk(&student)
+	// Write only the students that changed
+	outcomes := make(map[string]string, len(students))
+	var changed []models.Student
+	var changedEvaluations []models.RiskEvaluation
+	for i := range students {
+		if stored, ok := current[students[i].StudentID]; ok && isUnchanged(stored, &students[i], &evaluations[i]) {
+			outcomes[students[i].StudentID] = OutcomeUnchanged
+			continue
+		}
+		changed = append(changed, students[i])
+		changedEvaluations = append(changedEvaluations, evaluations[i])
+	}
+	if len(changed) > 0 {
+		created, err := tx.UpsertStudents(changed, changedEvaluations)
 		if err != nil {
-			tx.Rollback()
-			return nil, err
+			return err
 		}
-
-		// Update student record with risk evaluation
-		if err := tx.Model(&student).Updates(map[string]interface{}{
-			"dropout_score":      score,
-			"dropout_risk_level": riskLevel,
-			"dropout_note":       note,
-		}).Error; err != nil {
-			tx.Rollback()
-			return nil, err
+		for i := range changed {
+			outcomes[changed[i].StudentID] = OutcomeUpdated
+			if created[i] {
+				outcomes[changed[i].StudentID] = OutcomeCreated
+			}
 		}
+	}
 
-		// Get updated student record
-		if err := tx.First(&student, student.ID).Error; err != nil {
-			tx.Rollback()
-			return nil, err
-		}
-		
-		updatedStudents = append(updatedStudents, student)
+	stored, err := tx.FindStudents(studentIDs, repository.FindOptions{WithRecords: true})
+	if err != nil {
+		return err
+	}
+	if len(stored) != len(students) {
+		return fmt.Errorf("stored %d students but found %d", len(students), len(stored))
 	}
 
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		return nil, err
+	for i := range stored {
+		onStudent(stored[i], outcomes[stored[i].StudentID])
 	}
+	return nil
+}
 
-	return updatedStudents, nil
+// isUnchanged reports whether the stored student already holds the name and records
+// of input, as recorded by its input hash, and the risk evaluation it scores
+func isUnchanged(stored, input *models.Student, evaluation *models.RiskEvaluation) bool {
+	return !stored.DeletedAt.Valid &&
+		stored.InputHash != nil && input.InputHash != nil && *stored.InputHash == *input.InputHash &&
+		hasEvaluation(stored, evaluation)
 }
 
-// GetAllStudents retrieves all students with their risk evaluations
-func (s *StudentService) GetAllStudents() ([]models.Student, error) {
-	var students []models.Student
-	if err := s.db.Find(&students).Error; err != nil {
+// hasEvaluation reports whether the stored student already holds everything
+// evaluation sets on it: score, risk level, note, rates and factors
+func hasEvaluation(stored *models.Student, evaluation *models.RiskEvaluation) bool {
+	return stored.DropoutScore != nil && *stored.DropoutScore == evaluation.Score &&
+		stored.DropoutRiskLevel != nil && *stored.DropoutRiskLevel == string(evaluation.RiskLevel) &&
+		stored.DropoutNote != nil && *stored.DropoutNote == evaluation.Note &&
+		stored.AttendanceRate != nil && *stored.AttendanceRate == evaluation.AttendanceRate &&
+		stored.AssignmentRate != nil && *stored.AssignmentRate == evaluation.AssignmentRate &&
+		stored.ContactFailures != nil && *stored.ContactFailures == evaluation.ContactFailures &&
+		sameFactors(stored.DropoutFactors, evaluation.Factors)
+}
+
+// sameFactors reports whether two factor lists are equal, an empty list being equal to none
+func sameFactors(a, b models.RiskFactors) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// ProcessAndEvaluateStudentsPartial processes students like ProcessAndEvaluateStudents,
+// but a failing student is rolled back and reported without affecting the rest of the batch.
+// Each bulk upsert runs in its own savepoint; when one fails, its students are retried
+// one at a time, each in its own savepoint, to isolate the failing ones.
+func (s *StudentService) ProcessAndEvaluateStudentsPartial(students []models.Student) (*IngestionReport, error) {
+	report := &IngestionReport{
+		Created:   []models.Student{},
+		Updated:   []models.Student{},
+		Unchanged: []models.Student{},
+		Failed:    []IngestionFailure{},
+	}
+	record := func(student models.Student, outcome string) {
+		switch outcome {
+		case OutcomeCreated:
+			report.Created = append(report.Created, student)
+		case OutcomeUpdated:
+			report.Updated = append(report.Updated, student)
+		default:
+			report.Unchanged = append(report.Unchanged, student)
+		}
+	}
+
+	evaluations := s.scoreStudents(students)
+	err := s.repo.Transaction(func(tx repository.Repository) error {
+		for _, batch := range s.batches(students) {
+			var stored []models.Student
+			var outcomes []string
+			err := tx.Transaction(func(tx repository.Repository) error {
+				return s.upsertBatch(tx, students[batch[0]:batch[1]], evaluations[batch[0]:batch[1]], func(student models.Student, outcome string) {
+					stored = append(stored, student)
+					outcomes = append(outcomes, outcome)
+				})
+			})
+			if err == nil {
+				for i := range stored {
+					record(stored[i], outcomes[i])
+				}
+				continue
+			}
+
+			// Process each student of the failed batch in a nested transaction
+			for i := batch[0]; i < batch[1]; i++ {
+				var student models.Student
+				var created bool
+				err := tx.Transaction(func(tx repository.Repository) error {
+					var err error
+					student, created, err = s.processStudent(tx, &students[i])
+					return err
+				})
+				if err != nil {
+					report.Failed = append(report.Failed, IngestionFailure{
+						StudentID: students[i].StudentID,
+						Reason:    err.Error(),
+					})
+					continue
+				}
+				outcome := OutcomeUpdated
+				if created {
+					outcome = OutcomeCreated
+				}
+				record(student, outcome)
+			}
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
-	return students, nil
+
+	return report, nil
 }
 
-// GetStudentsWithFilters retrieves students with filtering and sorting options
-func (s *StudentService) GetStudentsWithFilters(riskLevel, sortBy string) ([]models.Student, error) {
-	var students []models.Student
-	query := s.db
-	
-	// Apply risk level filter if provided
-	if riskLevel != "" {
-		query = query.Where("dropout_risk_level = ?", riskLevel)
-	}
-	
-	// Apply sorting if provided
-	if sortBy == "risk_level" {
-		// Custom sorting order for risk levels: HIGH, MEDIUM, LOW
-		query = query.Order("CASE dropout_risk_level " +
-			"WHEN 'HIGH' THEN 1 " +
-			"WHEN 'MEDIUM' THEN 2 " +
-			"WHEN 'LOW' THEN 3 " +
-			"ELSE 4 END")
-	} else if sortBy == "risk_level_asc" {
-		// Custom sorting order for risk levels: LOW, MEDIUM, HIGH
-		query = query.Order("CASE dropout_risk_level " +
-			"WHEN 'LOW' THEN 1 " +
-			"WHEN 'MEDIUM' THEN 2 " +
-			"WHEN 'HIGH' THEN 3 " +
-			"ELSE 4 END")
-	} else if sortBy == "score" {
-		query = query.Order("dropout_score DESC")
-	} else if sortBy == "score_asc" {
-		query = query.Order("dropout_score ASC")
+// processStudent upserts a single student within tx, replaces its activity records,
+// evaluates its risk and returns the stored record along with whether it was newly created
+func (s *StudentService) processStudent(tx repository.Repository, input *models.Student) (models.Student, bool, error) {
+	// Check if student already exists, including deleted students that keep their student_id
+	existingStudent, err := tx.FindStudent(input.StudentID, repository.FindOptions{IncludeDeleted: true})
+
+	var student models.Student
+	created := false
+	if err == nil {
+		// Student exists, update record and restore it if it was deleted
+		if err := tx.UpdateStudent(existingStudent.ID, input.StudentName); err != nil {
+			return student, false, err
+		}
+		student = *existingStudent
+		created = existingStudent.DeletedAt.Valid
+	} else if errors.Is(err, repository.ErrNotFound) {
+		// Student doesn't exist, create new record; its activity records are stored below
+		if err := tx.CreateStudent(input); err != nil {
+			return student, false, err
+		}
+		student = *input
+		created = true
 	} else {
-		// Default sorting by student_id
-		query = query.Order("student_id")
+		// Other error
+		return student, false, err
 	}
-	
-	if err := query.Find(&students).Error; err != nil {
-		return nil, err
+
+	// Replace the student's activity records with the ones sent
+	student.Attendance = input.Attendance
+	student.Assignments = input.Assignments
+	student.Contacts = input.Contacts
+	if err := tx.ReplaceRecords(student.ID, &student); err != nil {
+		return student, false, err
 	}
-	
-	return students, nil
+
+	student, err = s.evaluateStudent(tx, &student)
+	return student, created, err
 }
 
-// evaluateRisk evaluates the dropout risk for a student
-func (s *StudentService) evaluateRisk(student *models.Student) (int, string, string, error) {
-	attendanceRecords, err := student.GetAttendanceRecords()
-	if err != nil {
-		return 0, "", "", fmt.Errorf("failed to parse attendance data: %w", err)
+// evaluateStudent evaluates the risk of a stored student from the activity records it holds,
+// stores the result with the student, appends it to the evaluation history and
+// returns the reloaded student
+func (s *StudentService) evaluateStudent(tx repository.Repository, student *models.Student) (models.Student, error) {
+	// Evaluate risk
+	evaluation := EvaluateStudent(s.scorer, student)
+
+	// Store the evaluation with the student and in its history
+	if err := tx.SaveEvaluation(&evaluation); err != nil {
+		return *student, err
 	}
 
-	assignmentRecords, err := student.GetAssignmentRecords()
+	// Get updated student record
+	updated, err := tx.FindStudent(student.StudentID, repository.FindOptions{WithRecords: true})
 	if err != nil {
-		return 0, "", "", fmt.Errorf("failed to parse assignment data: %w", err)
+		return *student, err
 	}
 
-	contactRecords, err := student.GetContactRecords()
+	return *updated, nil
+}
+
+// CreateStudent stores and evaluates a new student.
+// It returns ErrStudentExists if a student with the same student_id exists.
+func (s *StudentService) CreateStudent(input models.Student) (*models.Student, error) {
+	var student models.Student
+	err := s.repo.Transaction(func(tx repository.Repository) error {
+		_, err := tx.FindStudent(input.StudentID, repository.FindOptions{})
+		if err == nil {
+			return ErrStudentExists
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+
+		student, _, err = s.processStudent(tx, &input)
+		return err
+	})
 	if err != nil {
-		return 0, "", "", fmt.Errorf("failed to parse contact data: %w", err)
+		return nil, err
 	}
+	return &student, nil
+}
 
-	// Calculate risk factors
-	var riskFactors []string
-	score := 0
+// UpdateStudent applies a JSON student object to an existing student and re-evaluates it.
+// With replace set the object replaces the whole record, otherwise only the fields it contains change.
+// It returns repository.ErrNotFound if the student does not exist and ValidationErrors
+// if the updated record is invalid.
+func (s *StudentService) UpdateStudent(studentID string, data []byte, replace bool) (*models.Student, error) {
+	var student models.Student
+	err := s.repo.Transaction(func(tx repository.Repository) error {
+		existing, err := tx.FindStudent(studentID, repository.FindOptions{WithRecords: true, ForUpdate: true})
+		if err != nil {
+			return err
+		}
 
-	// Attendance risk
-	attendanceRate := s.calculateAttendanceRate(attendanceRecords)
-	if attendanceRate < s.config.AttendanceThreshold {
-		riskFactors = append(riskFactors, "attendance")
-		score++
-	}
+		input, err := DecodeStudentUpdate(existing, data, replace)
+		if err != nil {
+			return err
+		}
 
-	// Assignment risk
-	assignmentRate := s.calculateAssignmentRate(assignmentRecords)
-	if assignmentRate < s.config.AssignmentThreshold {
-		riskFactors = append(riskFactors, "assignment")
-		score++
+		student, _, err = s.processStudent(tx, &input)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	return &student, nil
+}
 
-	// Contact risk
-	contactFailures := s.countContactFailures(contactRecords)
-	if contactFailures >= s.config.ContactThreshold {
-		riskFactors = append(riskFactors, "communication")
-		score++
-	}
+// AppendStudentRecords appends attendance, assignment or contact records to a student
+// and re-evaluates its risk when any record was added. data holds one record or a JSON
+// array of records; records the student already has are skipped.
+// It returns repository.ErrNotFound if the student does not exist and ValidationErrors
+// if a record is invalid.
+func (s *StudentService) AppendStudentRecords(studentID, recordType string, data []byte) (*AppendResult, error) {
+	result := &AppendResult{}
+	err := s.repo.Transaction(func(tx repository.Repository) error {
+		// Lock the student so that concurrent appends see each other's records
+		existing, err := tx.FindStudent(studentID, repository.FindOptions{WithRecords: true, ForUpdate: true})
+		if err != nil {
+			return err
+		}
 
-	// Determine risk level
-	var riskLevel string
-	switch {
-	case score >= s.config.HighRiskThreshold:
-		riskLevel = string(models.RiskLevelHigh)
-	case score >= s.config.MediumRiskThreshold:
-		riskLevel = string(models.RiskLevelMedium)
-	default:
-		riskLevel = string(models.RiskLevelLow)
-	}
+		merged, added, duplicates, err := appendStudentRecords(existing, recordType, data)
+		if err != nil {
+			return err
+		}
+		result.Added = len(added.Attendance) + len(added.Assignments) + len(added.Contacts)
+		result.Duplicates = duplicates
 
-	// Create note
-	var note string
-	if len(riskFactors) > 0 {
-		note = strings.Join(riskFactors, ", ") + " risk factors"
-	} else {
-		note = "No signs of disengagement detected"
+		// Nothing changed, keep the current evaluation
+		if result.Added == 0 {
+			result.Student = existing
+			return nil
+		}
+
+		// Store only the new records and re-evaluate from the merged ones
+		if err := tx.AddRecords(existing.ID, &added); err != nil {
+			return err
+		}
+		merged.ID = existing.ID
+		student, err := s.evaluateStudent(tx, &merged)
+		if err != nil {
+			return err
+		}
+		result.Student = &student
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return result, nil
+}
 
-	return score, riskLevel, note, nil
+// DeleteStudent soft-deletes a student, keeping its evaluation history.
+// It returns repository.ErrNotFound if the student does not exist.
+func (s *StudentService) DeleteStudent(studentID string) error {
+	return s.repo.DeleteStudent(studentID)
 }
 
-// calculateAttendanceRate calculates the attendance rate as a percentage
-func (s *StudentService) calculateAttendanceRate(attendance []models.AttendanceRecord) float64 {
-	if len(attendance) == 0 {
-		return 100.0
+// GetAllStudents retrieves all students with their risk evaluations
+func (s *StudentService) GetAllStudents() ([]models.Student, error) {
+	students, _, err := s.repo.ListStudents(repository.ListOptions{
+		Sort: []repository.SortKey{{Field: repository.SortStudentID}},
+	})
+	if err != nil {
+		return nil, err
 	}
+	return students, nil
+}
 
-	attended := 0
-	for _, a := range attendance {
-		if a.Status == "ATTEND" {
-			attended++
-		}
+// GetStudentsWithFilters retrieves one page of students with filtering and sorting options.
+// It returns a *QueryError for invalid filters or sort fields, and ErrInvalidCursor
+// if q.Cursor is malformed or belongs to another sort order.
+func (s *StudentService) GetStudentsWithFilters(q StudentQuery) (*StudentPage, error) {
+	// Validate filters, sorting and fields before touching the repository
+	filter, err := studentFilter(q)
+	if err != nil {
+		return nil, err
+	}
+	keys, sortBy, err := parseStudentSort(q.SortBy)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := parseStudentFields(q.Fields)
+	if err != nil {
+		return nil, err
 	}
 
-	return float64(attended) / float64(len(attendance)) * 100.0
-}
-
-// calculateAssignmentRate calculates the assignment completion rate as a percentage
-func (s *StudentService) calculateAssignmentRate(assignments []models.AssignmentRecord) float64 {
-	if len(assignments) == 0 {
-		return 100.0
+	if q.Limit <= 0 {
+		q.Limit = DefaultStudentPageSize
+	}
+	if q.Limit > MaxStudentPageSize {
+		q.Limit = MaxStudentPageSize
 	}
 
-	submitted := 0
-	for _, a := range assignments {
-		if a.Submitted {
-			submitted++
+	// Sort always ends with student_id so that pages are stable; fetch one
+	// extra student to detect a following page
+	opts := repository.ListOptions{
+		Filter: filter,
+		Sort:   keys,
+		Limit:  q.Limit + 1,
+		Fields: fields,
+	}
+	if q.Cursor != "" {
+		if opts.After, err = decodeCursor(sortBy, keys, q.Cursor); err != nil {
+			return nil, err
 		}
+	} else {
+		opts.Offset = q.Offset
 	}
 
-	return float64(submitted) / float64(len(assignments)) * 100.0
-}
+	students, total, err := s.repo.ListStudents(opts)
+	if err != nil {
+		return nil, err
+	}
 
-// countContactFailures counts the number of failed contact attempts
-func (s *StudentService) countContactFailures(contacts []models.ContactRecord) int {
-	failures := 0
-	for _, c := range contacts {
-		if c.Status == "FAILED" {
-			failures++
+	page := &StudentPage{
+		Total:  total,
+		Limit:  q.Limit,
+		Offset: q.Offset,
+	}
+	if len(students) > q.Limit {
+		students = students[:q.Limit]
+		cursor, err := encodeCursor(sortBy, keys, &students[len(students)-1])
+		if err != nil {
+			return nil, err
 		}
+		page.NextCursor = cursor
 	}
-	return failures
+	page.Students = make([]StudentView, len(students))
+	for i := range students {
+		page.Students[i] = StudentView{fields: fields, student: &students[i]}
+	}
+
+	return page, nil
+}
+
+// GetStudent retrieves a single student with all of its records.
+// It returns repository.ErrNotFound if the student does not exist.
+func (s *StudentService) GetStudent(studentID string) (*models.Student, error) {
+	return s.repo.FindStudent(studentID, repository.FindOptions{WithRecords: true})
 }
 
+// GetStudentEvaluations retrieves the evaluation history of a student, oldest first.
+// The history outlives a soft delete, so deleted students are included.
+// It returns repository.ErrNotFound if the student never existed.
+func (s *StudentService) GetStudentEvaluations(studentID string) ([]models.RiskEvaluation, error) {
+	student, err := s.repo.FindStudent(studentID, repository.FindOptions{IncludeDeleted: true})
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListEvaluations(student.ID)
+}
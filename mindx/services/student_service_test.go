@@ -0,0 +1,271 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"mindx/config"
+	"mindx/database"
+	"mindx/models"
+	"mindx/repository"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// benchmarkStudents is the number of students ingested by each benchmark iteration
+const benchmarkStudents = 500
+
+// testConfig is the configuration of the services under test: count scoring with
+// the default thresholds, whatever RISK_* variables are set
+func testConfig() *config.Config {
+	return &config.Config{
+		Risk: config.RiskConfig{
+			AttendanceThreshold: 75,
+			AssignmentThreshold: 50,
+			ContactThreshold:    2,
+			MediumRiskThreshold: 2,
+			HighRiskThreshold:   3,
+			ScoringMode:         config.ScoringModeCount,
+		},
+		Ingest: config.IngestConfig{BatchSize: 2, Concurrency: 2},
+	}
+}
+
+// forEachRepository runs test against a new MemoryRepository and a new SQLite
+// GormRepository, so that both backends are held to the same behaviour
+func forEachRepository(t *testing.T, test func(t *testing.T, repo repository.Repository)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, repository.NewMemoryRepository())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		test(t, openSQLite(t))
+	})
+}
+
+// testStudent returns a student with attended sessions on the first days of June
+// 2025 followed by absences, all assignments submitted and no failed contacts
+func testStudent(studentID string, attended, absent int) models.Student {
+	student := models.Student{StudentID: studentID, StudentName: "Student " + studentID}
+	for day := 1; day <= attended+absent; day++ {
+		status := models.AttendanceStatusAttend
+		if day > attended {
+			status = models.AttendanceStatusAbsent
+		}
+		student.Attendance = append(student.Attendance, models.AttendanceRecord{
+			Date:   fmt.Sprintf("2025-06-%02d", day),
+			Status: status,
+		})
+	}
+	student.Assignments = []models.AssignmentRecord{{Date: "2025-06-07", Name: "HW 1", Submitted: true}}
+	student.Contacts = []models.ContactRecord{{Date: "2025-06-07", Status: models.ContactStatusSuccess}}
+	return student
+}
+
+func TestDeleteAndRestoreStudent(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.Repository) {
+		service := NewStudentService(repo, testConfig())
+
+		created, err := service.CreateStudent(testStudent("S1", 10, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := service.DeleteStudent("S1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := service.DeleteStudent("S1"); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("deleting again: got %v, want ErrNotFound", err)
+		}
+
+		// A deleted student is hidden but keeps its history
+		if _, err := service.GetStudent("S1"); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("GetStudent after delete: got %v, want ErrNotFound", err)
+		}
+		history, err := service.GetStudentEvaluations("S1")
+		if err != nil {
+			t.Fatalf("GetStudentEvaluations after delete: %v", err)
+		}
+		if len(history) != 1 || history[0].RiskLevel != models.RiskLevelLow {
+			t.Fatalf("history after delete = %+v, want one LOW evaluation", history)
+		}
+		if _, err := service.GetStudentEvaluations("S2"); !errors.Is(err, repository.ErrNotFound) {
+			t.Fatalf("GetStudentEvaluations of unknown student: got %v, want ErrNotFound", err)
+		}
+
+		// Creating the student again restores it under the same ID, with the
+		// new data and an evaluation appended to its history
+		restored, err := service.CreateStudent(testStudent("S1", 2, 8))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored.ID != created.ID {
+			t.Errorf("restored ID = %s, want %s", restored.ID, created.ID)
+		}
+		stored, err := service.GetStudent("S1")
+		if err != nil {
+			t.Fatalf("GetStudent after restore: %v", err)
+		}
+		if len(stored.Attendance) != 10 || *stored.DropoutRiskLevel != string(models.RiskLevelLow) || *stored.DropoutScore != 1 {
+			t.Errorf("restored student has %d attendance records, score %d, level %s; want 10, 1, LOW",
+				len(stored.Attendance), *stored.DropoutScore, *stored.DropoutRiskLevel)
+		}
+		history, err = service.GetStudentEvaluations("S1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 2 || history[1].Score != 1 {
+			t.Errorf("history after restore = %+v, want two evaluations ending with score 1", history)
+		}
+		if _, err := service.CreateStudent(testStudent("S1", 10, 0)); !errors.Is(err, ErrStudentExists) {
+			t.Errorf("creating a restored student: got %v, want ErrStudentExists", err)
+		}
+	})
+}
+
+func TestProcessAndEvaluateStudentsPartialRestoresDeleted(t *testing.T) {
+	forEachRepository(t, func(t *testing.T, repo repository.Repository) {
+		service := NewStudentService(repo, testConfig())
+
+		if _, err := service.ProcessAndEvaluateStudents([]models.Student{testStudent("S1", 10, 0), testStudent("S2", 10, 0)}); err != nil {
+			t.Fatal(err)
+		}
+		if err := service.DeleteStudent("S1"); err != nil {
+			t.Fatal(err)
+		}
+
+		// The deleted student is created again, the other one is unchanged
+		report, err := service.ProcessAndEvaluateStudentsPartial([]models.Student{testStudent("S1", 10, 0), testStudent("S2", 10, 0)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Created) != 1 || report.Created[0].StudentID != "S1" || len(report.Unchanged) != 1 || len(report.Updated) != 0 {
+			t.Fatalf("report = %d created, %d updated, %d unchanged; want S1 created and S2 unchanged",
+				len(report.Created), len(report.Updated), len(report.Unchanged))
+		}
+		history, err := service.GetStudentEvaluations("S1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 2 {
+			t.Errorf("history of restored student has %d evaluations, want 2", len(history))
+		}
+	})
+}
+
+// BenchmarkProcessAndEvaluateStudents compares batch ingestion, which scores students
+// in parallel and writes them with bulk upserts, with the serial path it replaced,
+// which found, wrote and evaluated one student at a time, on the SQLite backend.
+// "create" ingests new students every iteration, "update" changes the name and
+// records of the same ones. The memory backend is left out: it has no round-trips
+// for bulk upserts to save, so it says nothing about them.
+func BenchmarkProcessAndEvaluateStudents(b *testing.B) {
+	paths := []struct {
+		name    string
+		process func(s *StudentService, students []models.Student) error
+	}{
+		{"serial", processStudentsSerially},
+		{"bulk", func(s *StudentService, students []models.Student) error {
+			_, err := s.ProcessAndEvaluateStudents(students)
+			return err
+		}},
+	}
+
+	cfg := config.LoadConfig()
+	for _, path := range paths {
+		b.Run(path.name+"/create", func(b *testing.B) {
+			service := NewStudentService(openSQLite(b), cfg)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				students := benchmarkData(fmt.Sprintf("C%d-", i), 0)
+				b.StartTimer()
+				if err := path.process(service, students); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(path.name+"/update", func(b *testing.B) {
+			service := NewStudentService(openSQLite(b), cfg)
+			if err := path.process(service, benchmarkData("U-", 0)); err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				students := benchmarkData("U-", i+1)
+				b.StartTimer()
+				if err := path.process(service, students); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// openSQLite returns a GormRepository on a new SQLite database in a temporary directory
+func openSQLite(tb testing.TB) repository.Repository {
+	db, err := database.InitDB(config.DatabaseConfig{
+		Driver: config.DriverSQLite,
+		Path:   filepath.Join(tb.TempDir(), "mindx.db"),
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	// Keep the lookups of students not stored yet out of the log
+	return repository.NewGormRepository(db.Session(&gorm.Session{Logger: logger.Discard}))
+}
+
+// processStudentsSerially stores students the way batch ingestion did before bulk
+// upserts: one student at a time, each looked up, written and evaluated in turn
+func processStudentsSerially(s *StudentService, students []models.Student) error {
+	return s.repo.Transaction(func(tx repository.Repository) error {
+		for i := range students {
+			if _, _, err := s.processStudent(tx, &students[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// benchmarkData returns benchmarkStudents students whose IDs start with prefix.
+// Their names and records differ for each version, so that every version is written.
+func benchmarkData(prefix string, version int) []models.Student {
+	students := make([]models.Student, benchmarkStudents)
+	for i := range students {
+		student := models.Student{
+			StudentID:   fmt.Sprintf("%s%05d", prefix, i),
+			StudentName: fmt.Sprintf("Student %d v%d", i, version),
+		}
+		for day := 1; day <= 30; day++ {
+			status := models.AttendanceStatusAttend
+			if (i+day+version)%4 == 0 {
+				status = models.AttendanceStatusAbsent
+			}
+			student.Attendance = append(student.Attendance, models.AttendanceRecord{
+				Date:   fmt.Sprintf("2025-06-%02d", day),
+				Status: status,
+			})
+		}
+		for week := 1; week <= 4; week++ {
+			student.Assignments = append(student.Assignments, models.AssignmentRecord{
+				Date:      fmt.Sprintf("2025-06-%02d", week*7),
+				Name:      fmt.Sprintf("HW %d", week),
+				Submitted: (i+week+version)%3 != 0,
+			})
+			status := models.ContactStatusSuccess
+			if (i+week+version)%5 == 0 {
+				status = models.ContactStatusFailed
+			}
+			student.Contacts = append(student.Contacts, models.ContactRecord{
+				Date:   fmt.Sprintf("2025-06-%02d", week*7),
+				Status: status,
+			})
+		}
+		students[i] = student
+	}
+	return students
+}
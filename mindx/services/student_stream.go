@@ -0,0 +1,222 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"mindx/models"
+)
+
+// DefaultBatchSize is the number of students committed together by streaming ingestion
+const DefaultBatchSize = 500
+
+// ErrInvalidStream is returned when streamed student data is not a JSON array
+// or newline-delimited JSON objects
+var ErrInvalidStream = errors.New("invalid student data")
+
+// StudentDecoder reads students one at a time from a JSON array or from
+// newline-delimited JSON (one student object per line), so that files of any
+// size can be ingested with bounded memory. Only the student IDs already read
+// are kept, to reject duplicates.
+type StudentDecoder struct {
+	reader *bufio.Reader
+	dec    *json.Decoder
+	// array is set once the opening bracket of a JSON array was read
+	array bool
+	done  bool
+	index int
+	seen  map[string]int
+}
+
+// NewStudentDecoder creates a StudentDecoder reading from r
+func NewStudentDecoder(r io.Reader) *StudentDecoder {
+	return &StudentDecoder{
+		reader: bufio.NewReader(r),
+		seen:   make(map[string]int),
+	}
+}
+
+// Next decodes the next student. It returns io.EOF after the last student.
+// An invalid record is skipped and reported as ValidationErrors, after which
+// Next can be called again; any other error, ErrInvalidStream when the data is
+// malformed, ends the stream.
+func (d *StudentDecoder) Next() (models.Student, error) {
+	if d.done {
+		return models.Student{}, io.EOF
+	}
+	if d.dec == nil {
+		if err := d.start(); err != nil {
+			return models.Student{}, err
+		}
+	}
+
+	if !d.dec.More() {
+		d.done = true
+		if d.array {
+			// Consume the closing bracket
+			if _, err := d.dec.Token(); err != nil {
+				return models.Student{}, fmt.Errorf("%w: %v", ErrInvalidStream, err)
+			}
+		}
+		return models.Student{}, io.EOF
+	}
+
+	index := d.index
+	d.index++
+	var raw json.RawMessage
+	if err := d.dec.Decode(&raw); err != nil {
+		d.done = true
+		return models.Student{}, fmt.Errorf("%w: record %d: %v", ErrInvalidStream, index, err)
+	}
+
+	var record studentRecord
+	if err := unmarshalStrict(raw, &record); err != nil {
+		return models.Student{}, ValidationErrors{decodeFieldError(index, err)}
+	}
+	errs := validateStudentRecord(index, &record)
+	if record.StudentID != "" {
+		if first, ok := d.seen[record.StudentID]; ok {
+			errs = append(errs, FieldError{
+				Index:     index,
+				StudentID: record.StudentID,
+				Field:     "student_id",
+				Message:   fmt.Sprintf("duplicate of record %d", first),
+			})
+		} else if len(errs) == 0 {
+			d.seen[record.StudentID] = index
+		}
+	}
+	if len(errs) > 0 {
+		return models.Student{}, ValidationErrors(errs)
+	}
+	return record.toStudent(), nil
+}
+
+// start detects whether the data is a JSON array or newline-delimited JSON
+// from its first non-blank character
+func (d *StudentDecoder) start() error {
+	for {
+		b, err := d.reader.ReadByte()
+		if err == io.EOF {
+			d.done = true
+			return io.EOF
+		}
+		if err != nil {
+			return err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '[', '{':
+		default:
+			d.done = true
+			return fmt.Errorf("%w: expected a JSON array or newline-delimited JSON objects", ErrInvalidStream)
+		}
+
+		if err := d.reader.UnreadByte(); err != nil {
+			return err
+		}
+		d.dec = json.NewDecoder(d.reader)
+		if b == '[' {
+			// Consume the opening bracket so that elements decode one by one
+			d.array = true
+			if _, err := d.dec.Token(); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidStream, err)
+			}
+		}
+		return nil
+	}
+}
+
+// decodeStudentStream reads every student of a JSON array or newline-delimited
+// JSON like DecodeStudentsPartial
+func decodeStudentStream(r io.Reader) ([]models.Student, ValidationErrors, error) {
+	dec := NewStudentDecoder(r)
+	var students []models.Student
+	var errs ValidationErrors
+	for {
+		student, err := dec.Next()
+		if err == io.EOF {
+			return students, errs, nil
+		}
+		var validationErrs ValidationErrors
+		if errors.As(err, &validationErrs) {
+			errs = append(errs, validationErrs...)
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		students = append(students, student)
+	}
+}
+
+// StreamReport summarises a streaming ingestion run. Students are only counted,
+// so that the report stays small however many students the stream holds.
+type StreamReport struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	// Batches is the number of batches committed
+	Batches int                `json:"batches"`
+	Failed  []IngestionFailure `json:"failed"`
+}
+
+// ProcessStudentStream evaluates and stores the students read from dec in batches
+// of batchSize, each committed in its own transaction with a savepoint per student.
+// Invalid or failing students are reported and skipped. When reading the stream
+// fails, the batches committed so far stay stored and the report counts them.
+func (s *StudentService) ProcessStudentStream(dec *StudentDecoder, batchSize int) (*StreamReport, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	report := &StreamReport{Failed: []IngestionFailure{}}
+	batch := make([]models.Student, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		result, err := s.ProcessAndEvaluateStudentsPartial(batch)
+		if err != nil {
+			return err
+		}
+		report.Created += len(result.Created)
+		report.Updated += len(result.Updated)
+		report.Unchanged += len(result.Unchanged)
+		report.Failed = append(report.Failed, result.Failed...)
+		report.Batches++
+		batch = batch[:0]
+		return nil
+	}
+
+	for {
+		student, err := dec.Next()
+		if err == io.EOF {
+			break
+		}
+		var validationErrs ValidationErrors
+		if errors.As(err, &validationErrs) {
+			report.Failed = append(report.Failed, validationErrs.Failures()...)
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+
+		batch = append(batch, student)
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return report, err
+	}
+	return report, nil
+}
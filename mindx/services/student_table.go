@@ -0,0 +1,344 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mindx/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Student data formats accepted for ingestion
+const (
+	FormatJSON = "json"
+	// FormatNDJSON is newline-delimited JSON, one student object per line
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+	FormatXLSX   = "xlsx"
+)
+
+// Columns of the long table format. Each row holds one attendance, assignment
+// or contact record of a student; type selects which.
+const (
+	ColumnStudentID   = "student_id"
+	ColumnStudentName = "student_name"
+	ColumnType        = "type"
+	ColumnDate        = "date"
+	ColumnStatus      = "status"
+	ColumnName        = "name"
+	ColumnSubmitted   = "submitted"
+)
+
+// requiredColumns must be present in the header of every table
+var requiredColumns = []string{ColumnStudentID, ColumnStudentName, ColumnType, ColumnDate}
+
+// ColumnMapping maps each column of the long table format to the header
+// used for it in the imported file
+type ColumnMapping map[string]string
+
+// DefaultColumnMapping reads every column from a header of the same name
+var DefaultColumnMapping = ColumnMapping{
+	ColumnStudentID:   ColumnStudentID,
+	ColumnStudentName: ColumnStudentName,
+	ColumnType:        ColumnType,
+	ColumnDate:        ColumnDate,
+	ColumnStatus:      ColumnStatus,
+	ColumnName:        ColumnName,
+	ColumnSubmitted:   ColumnSubmitted,
+}
+
+// NewColumnMapping returns DefaultColumnMapping with the headers in overrides,
+// keyed by column. It returns an error for unknown columns.
+func NewColumnMapping(overrides map[string]string) (ColumnMapping, error) {
+	mapping := make(ColumnMapping, len(DefaultColumnMapping))
+	for column, header := range DefaultColumnMapping {
+		mapping[column] = header
+	}
+	for column, header := range overrides {
+		if _, ok := DefaultColumnMapping[column]; !ok {
+			return nil, fmt.Errorf("unknown column %q in column mapping", column)
+		}
+		if strings.TrimSpace(header) == "" {
+			return nil, fmt.Errorf("column %q is mapped to an empty header", column)
+		}
+		mapping[column] = header
+	}
+	return mapping, nil
+}
+
+// FormatFromFileName returns the data format implied by a file name's extension,
+// defaulting to JSON
+func FormatFromFileName(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON
+	case ".csv":
+		return FormatCSV
+	case ".xlsx":
+		return FormatXLSX
+	default:
+		return FormatJSON
+	}
+}
+
+// DecodeStudentsAs decodes student data in format like DecodeStudents.
+// CSV and XLSX data is read in the long table format with columns.
+func DecodeStudentsAs(format string, data []byte, columns ColumnMapping) ([]models.Student, error) {
+	students, errs, err := DecodeStudentsPartialAs(format, data, columns)
+	if err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return students, nil
+}
+
+// DecodeStudentsPartialAs decodes student data in format like DecodeStudentsPartial.
+// For CSV and XLSX data, validation errors are indexed by file row, the header being
+// row 1, and a student with any invalid row is skipped as a whole.
+func DecodeStudentsPartialAs(format string, data []byte, columns ColumnMapping) ([]models.Student, ValidationErrors, error) {
+	var rows [][]string
+	var err error
+	switch format {
+	case "", FormatJSON:
+		return DecodeStudentsPartial(data)
+	case FormatNDJSON:
+		return decodeStudentStream(bytes.NewReader(data))
+	case FormatCSV:
+		rows, err = readCSVRows(data)
+	case FormatXLSX:
+		rows, err = readXLSXRows(data)
+	default:
+		return nil, nil, fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return decodeStudentTable(rows, columns)
+}
+
+// readCSVRows reads every row of a CSV file. Rows may have different lengths.
+func readCSVRows(data []byte) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	return reader.ReadAll()
+}
+
+// readXLSXRows reads the rows of the first sheet of an XLSX workbook as raw
+// cell values, so that dates come as serial numbers regardless of cell format
+func readXLSXRows(data []byte) ([][]string, error) {
+	file, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sheets := file.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, errors.New("workbook has no sheets")
+	}
+	return file.GetRows(sheets[0], excelize.Options{RawCellValue: true})
+}
+
+// tableStudent collects the rows of one student of a table
+type tableStudent struct {
+	record studentRecord
+	// firstRow is the file row the student first appears on
+	firstRow int
+	invalid  bool
+	dates    map[string]map[string]bool
+	names    map[string]bool
+}
+
+// decodeStudentTable groups the rows of a long format table into students,
+// in the order each student first appears
+func decodeStudentTable(rows [][]string, columns ColumnMapping) ([]models.Student, ValidationErrors, error) {
+	if len(rows) == 0 {
+		return nil, nil, errors.New("missing header row")
+	}
+
+	// Locate each column in the header
+	positions := make(map[string]int)
+	for i, header := range rows[0] {
+		header = strings.TrimSpace(header)
+		for column, mapped := range columns {
+			if strings.EqualFold(header, mapped) {
+				positions[column] = i
+			}
+		}
+	}
+	for _, column := range requiredColumns {
+		if _, ok := positions[column]; !ok {
+			return nil, nil, fmt.Errorf("missing column %q", columns[column])
+		}
+	}
+
+	var order []string
+	students := make(map[string]*tableStudent)
+	var errs ValidationErrors
+
+	for i, row := range rows[1:] {
+		rowNumber := i + 2
+		cell := func(column string) string {
+			if pos, ok := positions[column]; ok && pos < len(row) {
+				return strings.TrimSpace(row[pos])
+			}
+			return ""
+		}
+		if isBlankRow(row) {
+			continue
+		}
+
+		studentID := cell(ColumnStudentID)
+		var rowErrs ValidationErrors
+		fail := func(column, message string) {
+			rowErrs = append(rowErrs, FieldError{Index: rowNumber, StudentID: studentID, Field: column, Message: message})
+		}
+		if studentID == "" {
+			fail(ColumnStudentID, "is required")
+			errs = append(errs, rowErrs...)
+			continue
+		}
+
+		student, ok := students[studentID]
+		if !ok {
+			student = &tableStudent{
+				record:   studentRecord{StudentID: studentID},
+				firstRow: rowNumber,
+				dates:    map[string]map[string]bool{RecordTypeAttendance: {}, RecordTypeContacts: {}},
+				names:    make(map[string]bool),
+			}
+			students[studentID] = student
+			order = append(order, studentID)
+		}
+		if name := cell(ColumnStudentName); name != "" && student.record.StudentName == "" {
+			student.record.StudentName = name
+		}
+
+		date, msg := tableDate(cell(ColumnDate))
+		if msg != "" {
+			fail(ColumnDate, msg)
+		}
+
+		switch recordType := tableRecordType(cell(ColumnType)); recordType {
+		case RecordTypeAttendance, RecordTypeContacts:
+			status := strings.ToUpper(cell(ColumnStatus))
+			if recordType == RecordTypeAttendance && status != models.AttendanceStatusAttend && status != models.AttendanceStatusAbsent {
+				fail(ColumnStatus, fmt.Sprintf("must be one of %s, %s", models.AttendanceStatusAttend, models.AttendanceStatusAbsent))
+			}
+			if recordType == RecordTypeContacts && status != models.ContactStatusSuccess && status != models.ContactStatusFailed {
+				fail(ColumnStatus, fmt.Sprintf("must be one of %s, %s", models.ContactStatusSuccess, models.ContactStatusFailed))
+			}
+			if msg == "" && student.dates[recordType][date] {
+				fail(ColumnDate, fmt.Sprintf("duplicate %s date %s", strings.TrimSuffix(recordType, "s"), date))
+			}
+			if len(rowErrs) > 0 {
+				break
+			}
+			student.dates[recordType][date] = true
+			if recordType == RecordTypeAttendance {
+				student.record.Attendance = append(student.record.Attendance, models.AttendanceRecord{Date: date, Status: status})
+			} else {
+				student.record.Contacts = append(student.record.Contacts, models.ContactRecord{Date: date, Status: status})
+			}
+		case RecordTypeAssignments:
+			name := cell(ColumnName)
+			if name == "" {
+				fail(ColumnName, "is required")
+			} else if student.names[name] {
+				fail(ColumnName, "duplicate assignment name "+name)
+			}
+			submitted, err := parseSubmitted(cell(ColumnSubmitted))
+			if err != nil {
+				fail(ColumnSubmitted, err.Error())
+			}
+			if len(rowErrs) > 0 {
+				break
+			}
+			student.names[name] = true
+			student.record.Assignments = append(student.record.Assignments, models.AssignmentRecord{Date: date, Name: name, Submitted: submitted})
+		default:
+			fail(ColumnType, "must be one of attendance, assignment, contact")
+		}
+
+		if len(rowErrs) > 0 {
+			student.invalid = true
+			errs = append(errs, rowErrs...)
+		}
+	}
+
+	var decoded []models.Student
+	for _, studentID := range order {
+		student := students[studentID]
+		if student.record.StudentName == "" {
+			student.invalid = true
+			errs = append(errs, FieldError{Index: student.firstRow, StudentID: studentID, Field: ColumnStudentName, Message: "is required"})
+		}
+		if !student.invalid {
+			decoded = append(decoded, student.record.toStudent())
+		}
+	}
+
+	// Report errors in row order
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Index < errs[j].Index })
+	return decoded, errs, nil
+}
+
+// isBlankRow reports whether every cell of row is empty
+func isBlankRow(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// tableRecordType returns the record type named by a type cell, accepting
+// singular and plural names in any case
+func tableRecordType(value string) string {
+	switch strings.ToLower(value) {
+	case "attendance":
+		return RecordTypeAttendance
+	case "assignment", "assignments":
+		return RecordTypeAssignments
+	case "contact", "contacts":
+		return RecordTypeContacts
+	}
+	return ""
+}
+
+// excelEpoch is day 0 of Excel's 1900 date system, and maxExcelDate the serial of 9999-12-31
+var excelEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+const maxExcelDate = 2958465
+
+// tableDate returns the YYYY-MM-DD date of a date cell, which is either a date
+// in that format or an Excel date serial number. The message is set when it is invalid.
+func tableDate(value string) (string, string) {
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial > 0 && serial <= maxExcelDate {
+		return excelEpoch.AddDate(0, 0, int(serial)).Format(models.DateLayout), ""
+	}
+	return value, validateDate(value)
+}
+
+// parseSubmitted parses a submitted cell, accepting true/false, yes/no and 1/0 in any case
+func parseSubmitted(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "y", "1":
+		return true, nil
+	case "false", "no", "n", "0", "":
+		return false, nil
+	}
+	return false, errors.New("must be true or false")
+}
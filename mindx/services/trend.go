@@ -0,0 +1,171 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"mindx/config"
+	"mindx/models"
+)
+
+// TrendDetector flags declining engagement from dated attendance and assignment
+// records: a drop in rate between the latest window and the one before it, or a
+// current run of consecutive absences or missed assignments
+type TrendDetector struct {
+	windowDays    int
+	dropThreshold float64
+	missStreak    int
+	asOf          time.Time
+}
+
+// EngagementTrend is the outcome of trend detection for one student
+type EngagementTrend struct {
+	// Details describes each signal, e.g. "attendance declining: 90% → 50%"
+	Details []string
+	// Severity grades the strongest signal from 0 (none) to 1
+	Severity float64
+}
+
+// Detected reports whether any declining signal was found
+func (t EngagementTrend) Detected() bool {
+	return len(t.Details) > 0
+}
+
+// NewTrendDetector creates a TrendDetector from the risk configuration
+func NewTrendDetector(cfg *config.RiskConfig) TrendDetector {
+	return TrendDetector{
+		windowDays:    cfg.TrendWindowDays,
+		dropThreshold: cfg.TrendDropThreshold,
+		missStreak:    cfg.ConsecutiveMissThreshold,
+		asOf:          NewRateWindow(cfg).AsOf,
+	}
+}
+
+// Enabled reports whether either trend check is configured
+func (d TrendDetector) Enabled() bool {
+	return d.windowDays > 0 || d.missStreak > 0
+}
+
+// Detect looks for declining engagement in input. Windows end at input.AsOf,
+// or the configured as-of date, or else the latest dated record.
+func (d TrendDetector) Detect(input RiskInput) EngagementTrend {
+	attendance := make([]datedEvent, 0, len(input.Attendance))
+	for _, a := range input.Attendance {
+		attendance = append(attendance, datedEvent{date: a.Date, positive: a.Status == models.AttendanceStatusAttend})
+	}
+	assignments := make([]datedEvent, 0, len(input.Assignments))
+	for _, a := range input.Assignments {
+		assignments = append(assignments, datedEvent{date: a.Date, positive: a.Submitted})
+	}
+
+	attendance = sortedEvents(attendance)
+	assignments = sortedEvents(assignments)
+
+	anchor := input.AsOf
+	if anchor.IsZero() {
+		anchor = d.asOf
+	}
+	if anchor.IsZero() {
+		for _, events := range [][]datedEvent{attendance, assignments} {
+			if len(events) > 0 && events[len(events)-1].parsed.After(anchor) {
+				anchor = events[len(events)-1].parsed
+			}
+		}
+	}
+
+	var trend EngagementTrend
+	d.detectDrop(&trend, "attendance", attendance, anchor)
+	d.detectDrop(&trend, "assignment completion", assignments, anchor)
+	d.detectStreak(&trend, "absences", attendance, anchor)
+	d.detectStreak(&trend, "missed assignments", assignments, anchor)
+	return trend
+}
+
+// detectDrop compares the rate of the latest window with the previous one
+func (d TrendDetector) detectDrop(trend *EngagementTrend, label string, events []datedEvent, anchor time.Time) {
+	if d.windowDays <= 0 || d.dropThreshold <= 0 {
+		return
+	}
+
+	window := time.Duration(d.windowDays) * 24 * time.Hour
+	recentStart := anchor.Add(-window)
+	previousStart := recentStart.Add(-window)
+
+	var recent, previous, recentPositive, previousPositive int
+	for _, e := range events {
+		switch {
+		case e.parsed.After(anchor) || !e.parsed.After(previousStart):
+		case e.parsed.After(recentStart):
+			recent++
+			if e.positive {
+				recentPositive++
+			}
+		default:
+			previous++
+			if e.positive {
+				previousPositive++
+			}
+		}
+	}
+	if recent == 0 || previous == 0 {
+		return
+	}
+
+	recentRate := float64(recentPositive) / float64(recent) * 100.0
+	previousRate := float64(previousPositive) / float64(previous) * 100.0
+	if previousRate-recentRate < d.dropThreshold {
+		return
+	}
+
+	trend.Details = append(trend.Details, fmt.Sprintf("%s declining: %.0f%% → %.0f%%", label, previousRate, recentRate))
+	trend.raise((previousRate - recentRate) / previousRate)
+}
+
+// detectStreak counts the run of negative events ending at the latest one
+func (d TrendDetector) detectStreak(trend *EngagementTrend, label string, events []datedEvent, anchor time.Time) {
+	if d.missStreak <= 0 {
+		return
+	}
+
+	streak := 0
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].parsed.After(anchor) {
+			continue
+		}
+		if events[i].positive {
+			break
+		}
+		streak++
+	}
+	if streak < d.missStreak {
+		return
+	}
+
+	trend.Details = append(trend.Details, fmt.Sprintf("%d consecutive %s", streak, label))
+	trend.raise(1)
+}
+
+// raise lifts the trend severity to severity if it is higher
+func (t *EngagementTrend) raise(severity float64) {
+	if severity > t.Severity {
+		t.Severity = severity
+	}
+}
+
+// sortedEvents parses event dates, drops undated events and sorts the rest oldest first
+func sortedEvents(events []datedEvent) []datedEvent {
+	dated := events[:0]
+	for _, e := range events {
+		parsed, err := time.Parse(models.DateLayout, e.date)
+		if err != nil {
+			continue
+		}
+		e.parsed = parsed
+		dated = append(dated, e)
+	}
+	sort.SliceStable(dated, func(i, j int) bool {
+		return dated[i].parsed.Before(dated[j].parsed)
+	})
+	return dated
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"math"
+
+	"mindx/config"
+	"mindx/models"
+)
+
+// WeightedScorer is a RiskScorer that grades each factor by how far it is past
+// its threshold and combines the weighted contributions into a 0-100 score.
+// A student at 10% attendance therefore scores much higher than one at 74%.
+type WeightedScorer struct {
+	config *config.RiskConfig
+	window RateWindow
+	trend  TrendDetector
+}
+
+// NewWeightedScorer creates a new WeightedScorer instance
+func NewWeightedScorer(cfg *config.RiskConfig) *WeightedScorer {
+	return &WeightedScorer{config: cfg, window: NewRateWindow(cfg), trend: NewTrendDetector(cfg)}
+}
+
+// Score implements RiskScorer
+func (w *WeightedScorer) Score(input RiskInput) models.RiskEvaluation {
+	var riskFactors []string
+	var factors models.RiskFactors
+	var weighted float64
+
+	// Weights are normalised so that contributions add up to the 0-100 score.
+	// The trend weight only counts when the trend factor is enabled.
+	trendWeight := 0.0
+	if w.trend.Enabled() {
+		trendWeight = w.config.TrendWeight
+	}
+	scale := 0.0
+	if total := w.config.AttendanceWeight + w.config.AssignmentWeight + w.config.ContactWeight + trendWeight; total > 0 {
+		scale = 100 / total
+	}
+
+	// Attendance risk grows linearly from 0 at the threshold to 1 at 0% attendance
+	attendanceRate := w.window.AttendanceRate(input.Attendance, input.AsOf)
+	weight := w.config.AttendanceWeight * scale
+	severity := rateSeverity(attendanceRate, w.config.AttendanceThreshold)
+	if severity > 0 {
+		riskFactors = append(riskFactors, "attendance")
+		weighted += severity * weight
+	}
+	factors = append(factors, rateFactor("attendance", attendanceRate, w.config.AttendanceThreshold, weight, severity*weight))
+
+	// Assignment risk grows linearly from 0 at the threshold to 1 at 0% submitted
+	assignmentRate := w.window.AssignmentRate(input.Assignments, input.AsOf)
+	weight = w.config.AssignmentWeight * scale
+	severity = rateSeverity(assignmentRate, w.config.AssignmentThreshold)
+	if severity > 0 {
+		riskFactors = append(riskFactors, "assignment")
+		weighted += severity * weight
+	}
+	factors = append(factors, rateFactor("assignment", assignmentRate, w.config.AssignmentThreshold, weight, severity*weight))
+
+	// Contact risk grows with each failure and saturates at the threshold
+	contactFailures := countContactFailures(input.Contacts)
+	weight = w.config.ContactWeight * scale
+	severity = countSeverity(contactFailures, w.config.ContactThreshold)
+	if severity > 0 {
+		riskFactors = append(riskFactors, "communication")
+		weighted += severity * weight
+	}
+	factors = append(factors, countFactor("communication", contactFailures, w.config.ContactThreshold, weight, severity*weight))
+
+	// Declining engagement contributes by the severity of its strongest signal,
+	// when the trend factor is enabled
+	var trend EngagementTrend
+	if w.trend.Enabled() {
+		trend = w.trend.Detect(input)
+		weight = trendWeight * scale
+		if trend.Detected() {
+			riskFactors = append(riskFactors, "engagement trend")
+			weighted += trend.Severity * weight
+		}
+		factors = append(factors, trendFactor(trend, weight, trend.Severity*weight))
+	}
+
+	score := int(math.Round(weighted))
+
+	return models.RiskEvaluation{
+		Score:               score,
+		RiskLevel:           w.riskLevel(score),
+		Note:                riskNote(riskFactors, trend.Details),
+		Factors:             factors,
+		AttendanceRate:      attendanceRate,
+		AssignmentRate:      assignmentRate,
+		ContactFailures:     contactFailures,
+		ScoringMode:         config.ScoringModeWeighted,
+		AttendanceThreshold: w.config.AttendanceThreshold,
+		AssignmentThreshold: w.config.AssignmentThreshold,
+		ContactThreshold:    w.config.ContactThreshold,
+		MediumRiskThreshold: w.config.WeightedMediumThreshold,
+		HighRiskThreshold:   w.config.WeightedHighThreshold,
+	}
+}
+
+// riskLevel maps a 0-100 score to a risk level using the weighted thresholds
+func (w *WeightedScorer) riskLevel(score int) models.RiskLevel {
+	switch {
+	case score >= w.config.WeightedHighThreshold:
+		return models.RiskLevelHigh
+	case score >= w.config.WeightedMediumThreshold:
+		return models.RiskLevelMedium
+	default:
+		return models.RiskLevelLow
+	}
+}
+
+// rateSeverity returns how far rate has fallen below threshold, from 0 to 1
+func rateSeverity(rate, threshold float64) float64 {
+	if threshold <= 0 || rate >= threshold {
+		return 0
+	}
+	return (threshold - rate) / threshold
+}
+
+// countSeverity returns count as a fraction of threshold, capped at 1
+func countSeverity(count, threshold int) float64 {
+	if count <= 0 {
+		return 0
+	}
+	if threshold <= 0 {
+		return 1
+	}
+	return math.Min(float64(count)/float64(threshold), 1)
+}